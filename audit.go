@@ -0,0 +1,370 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+/* ===============================
+   Audit
+================================= */
+
+const (
+	findingOrphanDatabase  = "orphan_database"
+	findingOrphanUser      = "orphan_user"
+	findingStaleRegistry   = "stale_registry"
+	findingUnexpectedGrant = "unexpected_grant"
+	findingMissingGrant    = "missing_grant"
+	findingWildcardHost    = "wildcard_host"
+)
+
+type AuditFinding struct {
+	Kind     string `json:"kind"`
+	Database string `json:"database,omitempty"`
+	Account  string `json:"account,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+type AuditReport struct {
+	Profile  string         `json:"profile"`
+	Findings []AuditFinding `json:"findings"`
+	Summary  map[string]int `json:"summary"`
+}
+
+// auditAccount is one account with its privileges as the server reports
+// them: global ones (USAGE excluded) and database-level ones per schema.
+type auditAccount struct {
+	User    string
+	Host    string
+	Global  []string
+	Schemas map[string][]string
+}
+
+// auditState is everything audit looks at, read in one pass so the rules
+// in auditFindings can be tested without a server.
+type auditState struct {
+	Schemas  map[string]bool
+	Accounts map[string]*auditAccount // keyed by 'user'@'host'
+	Registry []RegistryEntry
+}
+
+func (s *auditState) account(user, host string) *auditAccount {
+	key := quoteUserHost(user, host)
+	a, ok := s.Accounts[key]
+	if !ok {
+		a = &auditAccount{User: user, Host: host, Schemas: make(map[string][]string)}
+		s.Accounts[key] = a
+	}
+	return a
+}
+
+func loadAuditState(ctx context.Context, db *sql.DB) (*auditState, error) {
+	s := &auditState{Schemas: make(map[string]bool), Accounts: make(map[string]*auditAccount)}
+
+	rows, err := db.QueryContext(ctx, "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA")
+	if err != nil {
+		return nil, fmt.Errorf("list databases: %w", err)
+	}
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.Schemas[n] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// mysql.user (MariaDB 10.4 or later) is the account list; roles are
+	// skipped here and their grants below.
+	rows, err = db.QueryContext(ctx, "SELECT User, Host FROM mysql.user WHERE is_role = 'N'")
+	if err != nil {
+		return nil, fmt.Errorf("read mysql.user: %w", err)
+	}
+	for rows.Next() {
+		var u, h string
+		if err := rows.Scan(&u, &h); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.account(u, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, "SELECT GRANTEE, PRIVILEGE_TYPE FROM information_schema.USER_PRIVILEGES")
+	if err != nil {
+		return nil, fmt.Errorf("read user privileges: %w", err)
+	}
+	for rows.Next() {
+		var grantee, priv string
+		if err := rows.Scan(&grantee, &priv); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		u, h, ok := parseGrantee(grantee)
+		if !ok {
+			continue
+		}
+		a, ok := s.Accounts[quoteUserHost(u, h)]
+		if !ok {
+			continue
+		}
+		if priv != "USAGE" {
+			a.Global = append(a.Global, priv)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		"SELECT GRANTEE, TABLE_SCHEMA, PRIVILEGE_TYPE FROM information_schema.SCHEMA_PRIVILEGES")
+	if err != nil {
+		return nil, fmt.Errorf("read schema privileges: %w", err)
+	}
+	for rows.Next() {
+		var grantee, schema, priv string
+		if err := rows.Scan(&grantee, &schema, &priv); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		u, h, ok := parseGrantee(grantee)
+		if !ok {
+			continue
+		}
+		a, ok := s.Accounts[quoteUserHost(u, h)]
+		if !ok {
+			continue
+		}
+		a.Schemas[schema] = append(a.Schemas[schema], priv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.Registry, err = registryEntries(ctx, db, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// expectedPrivileges returns what account a should hold on database name:
+// the registered privileges, or for unregistered look-alikes the selected
+// profile (owner) or SELECT (_ro companion).
+func expectedPrivileges(a *auditAccount, name string, reg *RegistryEntry, profile PrivilegeProfile) []string {
+	if reg != nil {
+		if privs, err := parsePrivileges(reg.Privileges); err == nil {
+			return privs
+		}
+	}
+	if a.User == readOnlyUserName(name) {
+		return []string{"SELECT"}
+	}
+	return profile.Privileges
+}
+
+// auditFindings applies the audit rules to s. Managed-looking accounts are
+// registered ones and unregistered users named like a database (or its
+// _ro companion); only those are checked for drift.
+func auditFindings(s *auditState, profile PrivilegeProfile) []AuditFinding {
+	var findings []AuditFinding
+	add := func(kind, database, account, detail string) {
+		findings = append(findings, AuditFinding{Kind: kind, Database: database, Account: account, Detail: detail})
+	}
+
+	registered := make(map[string]*RegistryEntry)
+	for i := range s.Registry {
+		e := &s.Registry[i]
+		registered[quoteUserHost(e.User, e.Host)] = e
+	}
+
+	keys := make([]string, 0, len(s.Accounts))
+	for k := range s.Accounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	usersByName := make(map[string]bool)
+	for _, a := range s.Accounts {
+		usersByName[a.User] = true
+	}
+
+	// Databases without a user of the same name on any host.
+	schemas := make([]string, 0, len(s.Schemas))
+	for name := range s.Schemas {
+		schemas = append(schemas, name)
+	}
+	sort.Strings(schemas)
+	for _, name := range schemas {
+		if systemSchemas[name] || usersByName[name] {
+			continue
+		}
+		add(findingOrphanDatabase, name, "", fmt.Sprintf("no user named '%s' on any host", name))
+	}
+
+	for _, k := range keys {
+		a := s.Accounts[k]
+		reg := registered[k]
+
+		if hasWildcardHost(a.Host) {
+			add(findingWildcardHost, "", k, fmt.Sprintf("host '%s' matches more than one client", a.Host))
+		}
+
+		// Users whose database-level privileges all point at databases
+		// that no longer exist.
+		if len(a.Global) == 0 && len(a.Schemas) > 0 {
+			var missing []string
+			for schema := range a.Schemas {
+				if !s.Schemas[schema] {
+					missing = append(missing, schema)
+				}
+			}
+			if len(missing) == len(a.Schemas) {
+				sort.Strings(missing)
+				add(findingOrphanUser, missing[0], k,
+					fmt.Sprintf("privileges only on missing database(s): %s", strings.Join(missing, ", ")))
+				continue
+			}
+		}
+
+		// Drift, for registered accounts and unregistered look-alikes.
+		name := ""
+		switch {
+		case reg != nil:
+			name = reg.Database
+		case s.Schemas[a.User]:
+			name = a.User
+		case strings.HasSuffix(a.User, "_ro") && s.Schemas[strings.TrimSuffix(a.User, "_ro")]:
+			name = strings.TrimSuffix(a.User, "_ro")
+		}
+		if name == "" || !s.Schemas[name] {
+			continue
+		}
+
+		if len(a.Global) > 0 {
+			g := append([]string(nil), a.Global...)
+			sort.Strings(g)
+			add(findingUnexpectedGrant, name, k, "global privileges: "+strings.Join(g, ", "))
+		}
+		var others []string
+		for schema := range a.Schemas {
+			if schema != name {
+				others = append(others, schema)
+			}
+		}
+		if len(others) > 0 {
+			sort.Strings(others)
+			add(findingUnexpectedGrant, name, k, "privileges on other databases: "+strings.Join(others, ", "))
+		}
+
+		missing, extra := diffPrivileges(expectedPrivileges(a, name, reg, profile), a.Schemas[name])
+		if len(extra) > 0 {
+			add(findingUnexpectedGrant, name, k,
+				fmt.Sprintf("privileges beyond %s: %s", auditBaseline(reg, profile), strings.Join(extra, ", ")))
+		}
+		if len(missing) > 0 {
+			add(findingMissingGrant, name, k,
+				fmt.Sprintf("missing privileges of %s: %s", auditBaseline(reg, profile), strings.Join(missing, ", ")))
+		}
+	}
+
+	// Registry rows whose account or database is gone.
+	for _, e := range s.Registry {
+		k := quoteUserHost(e.User, e.Host)
+		switch {
+		case !s.Schemas[e.Database] && e.Role == roleOwner:
+			add(findingStaleRegistry, e.Database, k, "registered database no longer exists")
+		case s.Accounts[k] == nil:
+			add(findingStaleRegistry, e.Database, k, "registered account no longer exists")
+		}
+	}
+
+	return findings
+}
+
+func auditBaseline(reg *RegistryEntry, profile PrivilegeProfile) string {
+	if reg != nil {
+		return "registered privileges"
+	}
+	return "profile " + profile.Name
+}
+
+func summarizeFindings(findings []AuditFinding) map[string]int {
+	sum := make(map[string]int)
+	for _, f := range findings {
+		sum[f.Kind]++
+	}
+	return sum
+}
+
+func printAudit(w io.Writer, opts Options, r AuditReport) error {
+	if opts.Output != outputText {
+		return writeJSON(w, r)
+	}
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(w, "✅ No findings.")
+		return nil
+	}
+	for _, f := range r.Findings {
+		subject := f.Account
+		if subject == "" {
+			subject = f.Database
+		}
+		fmt.Fprintf(w, "⚠️  %-16s %-32s %s\n", f.Kind, subject, f.Detail)
+	}
+
+	kinds := make([]string, 0, len(r.Summary))
+	for k := range r.Summary {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = fmt.Sprintf("%d %s", r.Summary[k], k)
+	}
+	fmt.Fprintf(w, "%d findings (%s)\n", len(r.Findings), strings.Join(parts, ", "))
+	return nil
+}
+
+// runAudit prints the findings and returns how many there were.
+func runAudit(db *sql.DB, opts Options) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	s, err := loadAuditState(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	findings := auditFindings(s, opts.Privileges)
+	if findings == nil {
+		findings = []AuditFinding{}
+	}
+	r := AuditReport{Profile: opts.Privileges.Name, Findings: findings, Summary: summarizeFindings(findings)}
+	return len(findings), printAudit(os.Stdout, opts, r)
+}
@@ -0,0 +1,129 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testAuditState() *auditState {
+	s := &auditState{
+		Schemas:  map[string]bool{"mysql": true, "shop": true, "blog": true, "forum": true, registrySchema: true},
+		Accounts: make(map[string]*auditAccount),
+	}
+	s.account("root", "localhost").Global = []string{"SUPER"}
+	// shop: registered, widened by hand.
+	shop := s.account("shop", "localhost")
+	shop.Schemas["shop"] = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "DROP"}
+	shop.Schemas["forum"] = []string{"SELECT"}
+	s.account("shop_ro", "localhost").Schemas["shop"] = []string{"SELECT"}
+	// forum: unregistered, matches the profile but reachable from anywhere.
+	s.account("forum", "%").Schemas["forum"] = allPrivilegesExpanded
+	// wiki: database dropped by hand, grants left behind.
+	s.account("wiki", "localhost").Schemas["wiki"] = []string{"SELECT"}
+
+	s.Registry = []RegistryEntry{
+		{Database: "shop", User: "shop", Host: "localhost", Role: roleOwner, Privileges: "SELECT, INSERT, UPDATE, DELETE"},
+		{Database: "shop", User: "shop_ro", Host: "localhost", Role: roleReadOnly, Privileges: "SELECT"},
+		{Database: "news", User: "news", Host: "localhost", Role: roleOwner, Privileges: "ALL PRIVILEGES"},
+	}
+	return s
+}
+
+func TestAuditFindings(t *testing.T) {
+	profile, err := resolvePrivilegeProfile(nil, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := auditFindings(testAuditState(), profile)
+
+	got := make(map[string]string)
+	for _, f := range findings {
+		subject := f.Account
+		if subject == "" {
+			subject = f.Database
+		}
+		got[f.Kind+" "+subject] += f.Detail + ";"
+	}
+
+	want := map[string]string{
+		"orphan_database blog":                "no user named 'blog'",
+		"wildcard_host 'forum'@'%'":           "'%'",
+		"orphan_user 'wiki'@'localhost'":      "wiki",
+		"unexpected_grant 'shop'@'localhost'": "DROP",
+		"stale_registry 'news'@'localhost'":   "database no longer exists",
+	}
+	for k, detail := range want {
+		if !strings.Contains(got[k], detail) {
+			t.Errorf("missing finding %q (%q), got %v", k, detail, got)
+		}
+	}
+	if !strings.Contains(got["unexpected_grant 'shop'@'localhost'"], "other databases: forum") {
+		t.Errorf("grant on another database not flagged: %v", got)
+	}
+	for _, k := range []string{
+		"orphan_database shop", "orphan_database mysql", "orphan_database " + registrySchema,
+		"unexpected_grant 'shop_ro'@'localhost'", "unexpected_grant 'forum'@'%'",
+		"missing_grant 'forum'@'%'", "orphan_user 'root'@'localhost'",
+	} {
+		if _, ok := got[k]; ok {
+			t.Errorf("unexpected finding %q: %s", k, got[k])
+		}
+	}
+}
+
+func TestAuditFindingsUsesProfileForUnregistered(t *testing.T) {
+	s := &auditState{Schemas: map[string]bool{"shop": true}, Accounts: make(map[string]*auditAccount)}
+	s.account("shop", "localhost").Schemas["shop"] = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "DROP"}
+
+	profile, err := resolvePrivilegeProfile(nil, "readonly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, f := range auditFindings(s, profile) {
+		kinds = append(kinds, f.Kind)
+		if f.Kind == findingUnexpectedGrant && !strings.Contains(f.Detail, "profile readonly") {
+			t.Errorf("baseline not named: %s", f.Detail)
+		}
+	}
+	if strings.Join(kinds, ",") != "unexpected_grant,missing_grant" {
+		t.Fatalf("unexpected findings: %v", kinds)
+	}
+}
+
+func TestPrintAudit(t *testing.T) {
+	findings := []AuditFinding{
+		{Kind: findingOrphanDatabase, Database: "blog", Detail: "no user named 'blog' on any host"},
+		{Kind: findingWildcardHost, Account: "'forum'@'%'", Detail: "host '%' matches more than one client"},
+	}
+	var buf bytes.Buffer
+	r := AuditReport{Profile: "owner", Findings: findings, Summary: summarizeFindings(findings)}
+	if err := printAudit(&buf, Options{Output: outputText}, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "blog") || !strings.Contains(out, "'forum'@'%'") ||
+		!strings.Contains(out, "2 findings (1 orphan_database, 1 wildcard_host)") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	buf.Reset()
+	_ = printAudit(&buf, Options{Output: outputText}, AuditReport{Summary: map[string]int{}})
+	if !strings.Contains(buf.String(), "No findings") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
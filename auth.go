@@ -0,0 +1,200 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/* ===============================
+   Authentication plugins
+================================= */
+
+const pluginNative = "mysql_native_password"
+
+// authPlugins lists the plugins users can be created with, and whether
+// they take a password.
+var authPlugins = map[string]bool{
+	pluginNative:  true,
+	"ed25519":     true,
+	"unix_socket": false,
+}
+
+// AuthSpec is the authentication for created users: one plugin, or
+// several tried in order (IDENTIFIED VIA a OR b). Empty means
+// mysql_native_password via IDENTIFIED BY, as before plugins were
+// selectable.
+type AuthSpec struct {
+	Plugins []string
+}
+
+// authSonames names the library that provides each non-builtin plugin.
+var authSonames = map[string]string{
+	"ed25519":     "auth_ed25519",
+	"unix_socket": "auth_socket",
+}
+
+var authSplitRe = regexp.MustCompile(`(?i)\s*(?:,|\s+or\s+)\s*`)
+
+// parseAuth accepts "ed25519", "unix_socket,ed25519" or
+// "unix_socket OR ed25519".
+func parseAuth(s string) (AuthSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return AuthSpec{}, nil
+	}
+	var a AuthSpec
+	seen := make(map[string]bool)
+	for _, p := range authSplitRe.Split(s, -1) {
+		p = strings.ToLower(p)
+		if _, ok := authPlugins[p]; !ok {
+			return AuthSpec{}, fmt.Errorf("unsupported authentication plugin '%s' (supported: ed25519, mysql_native_password, unix_socket)", p)
+		}
+		if seen[p] {
+			return AuthSpec{}, fmt.Errorf("authentication plugin '%s' listed twice", p)
+		}
+		seen[p] = true
+		a.Plugins = append(a.Plugins, p)
+	}
+	return a, nil
+}
+
+func (a AuthSpec) isDefault() bool {
+	return len(a.Plugins) == 0 || (len(a.Plugins) == 1 && a.Plugins[0] == pluginNative)
+}
+
+// usesPassword reports whether any plugin takes a password. Users
+// without one get no generated password.
+func (a AuthSpec) usesPassword() bool {
+	if len(a.Plugins) == 0 {
+		return true
+	}
+	for _, p := range a.Plugins {
+		if authPlugins[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func (a AuthSpec) String() string {
+	if len(a.Plugins) == 0 {
+		return pluginNative
+	}
+	return strings.Join(a.Plugins, " OR ")
+}
+
+// identifiedSQL renders the IDENTIFIED clause. The default keeps the
+// plain IDENTIFIED BY form so existing plans stay valid.
+func (a AuthSpec) identifiedSQL(password string) string {
+	if a.isDefault() {
+		return " IDENTIFIED BY '" + escapeSQLStringLiteral(password) + "'"
+	}
+	parts := make([]string, len(a.Plugins))
+	for i, p := range a.Plugins {
+		parts[i] = p
+		if authPlugins[p] {
+			parts[i] += " USING PASSWORD('" + escapeSQLStringLiteral(password) + "')"
+		}
+	}
+	return " IDENTIFIED VIA " + strings.Join(parts, " OR ")
+}
+
+// rotatable reports whether a new password can be set without changing
+// how the account authenticates: every plugin must be known and one of
+// them must take a password.
+func (a AuthSpec) rotatable() bool {
+	for _, p := range a.Plugins {
+		if _, ok := authPlugins[p]; !ok {
+			return false
+		}
+	}
+	return a.usesPassword()
+}
+
+// checkAuthPlugins returns why a cannot be used on the server, or "" if
+// every plugin is loaded.
+func checkAuthPlugins(ctx context.Context, db *sql.DB, a AuthSpec) (string, error) {
+	for _, p := range a.Plugins {
+		var status string
+		err := db.QueryRowContext(ctx,
+			`SELECT PLUGIN_STATUS
+			 FROM information_schema.PLUGINS
+			 WHERE PLUGIN_NAME = ? AND PLUGIN_TYPE = 'AUTHENTICATION'`, p,
+		).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Sprintf("authentication plugin '%s' is not loaded (INSTALL SONAME '%s')", p, authSonames[p]), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("check authentication plugin: %w", err)
+		}
+		if status != "ACTIVE" {
+			return fmt.Sprintf("authentication plugin '%s' is %s", p, strings.ToLower(status)), nil
+		}
+	}
+	return "", nil
+}
+
+// accountAuth reads the plugins an existing account authenticates with
+// from mysql.global_priv (MariaDB 10.4+).
+func accountAuth(ctx context.Context, db *sql.DB, user, host string) (AuthSpec, error) {
+	var priv string
+	err := db.QueryRowContext(ctx,
+		`SELECT Priv FROM mysql.global_priv WHERE User = ? AND Host = ?`, user, host,
+	).Scan(&priv)
+	if err != nil {
+		return AuthSpec{}, fmt.Errorf("read authentication of %s: %w", quoteUserHost(user, host), err)
+	}
+	return authFromPriv(priv)
+}
+
+// authFromPriv extracts the plugins from a global_priv Priv document, in
+// the order they are tried. With alternatives, auth_or lists them and an
+// empty entry stands for the main plugin.
+func authFromPriv(priv string) (AuthSpec, error) {
+	var p struct {
+		Plugin string `json:"plugin"`
+		AuthOr []struct {
+			Plugin string `json:"plugin"`
+		} `json:"auth_or"`
+	}
+	if err := json.Unmarshal([]byte(priv), &p); err != nil {
+		return AuthSpec{}, fmt.Errorf("parse global_priv: %w", err)
+	}
+	var a AuthSpec
+	seen := make(map[string]bool)
+	add := func(plugin string) {
+		if plugin != "" && !seen[plugin] {
+			seen[plugin] = true
+			a.Plugins = append(a.Plugins, plugin)
+		}
+	}
+	if len(p.AuthOr) == 0 {
+		add(p.Plugin)
+	}
+	for _, o := range p.AuthOr {
+		if o.Plugin == "" {
+			add(p.Plugin)
+		} else {
+			add(o.Plugin)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,93 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAuth(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                         nil,
+		"ed25519":                  {"ed25519"},
+		"unix_socket,ED25519":      {"unix_socket", "ed25519"},
+		" unix_socket OR ed25519 ": {"unix_socket", "ed25519"},
+	} {
+		a, err := parseAuth(in)
+		if err != nil {
+			t.Fatalf("parseAuth(%q): %v", in, err)
+		}
+		if !slices.Equal(a.Plugins, want) {
+			t.Fatalf("parseAuth(%q)=%q, want %q", in, a.Plugins, want)
+		}
+	}
+	for _, in := range []string{"pam", "ed25519,ed25519", "unix_socket,", "ed25519 USING x"} {
+		if _, err := parseAuth(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestIdentifiedSQL(t *testing.T) {
+	cases := map[string]string{
+		"":                      " IDENTIFIED BY 'p''w'",
+		"mysql_native_password": " IDENTIFIED BY 'p''w'",
+		"ed25519":               " IDENTIFIED VIA ed25519 USING PASSWORD('p''w')",
+		"unix_socket,ed25519":   " IDENTIFIED VIA unix_socket OR ed25519 USING PASSWORD('p''w')",
+		"unix_socket":           " IDENTIFIED VIA unix_socket",
+	}
+	for in, want := range cases {
+		a, _ := parseAuth(in)
+		if got := a.identifiedSQL("p'w"); got != want {
+			t.Fatalf("%q: got %q, want %q", in, got, want)
+		}
+	}
+
+	socket, _ := parseAuth("unix_socket")
+	if socket.usesPassword() {
+		t.Fatal("unix_socket takes no password")
+	}
+}
+
+func TestAuthFromPriv(t *testing.T) {
+	cases := map[string][]string{
+		`{"access":0,"plugin":"mysql_native_password","authentication_string":"*AB"}`:              {"mysql_native_password"},
+		`{"plugin":"ed25519","authentication_string":"x","auth_or":[{"plugin":"unix_socket"},{}]}`: {"unix_socket", "ed25519"},
+		`{"plugin":"unix_socket"}`: {"unix_socket"},
+	}
+	for in, want := range cases {
+		a, err := authFromPriv(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(a.Plugins, want) {
+			t.Fatalf("authFromPriv(%s)=%q, want %q", in, a.Plugins, want)
+		}
+	}
+
+	for in, want := range map[string]bool{
+		"mysql_native_password":  true,
+		"unix_socket OR ed25519": true,
+		"unix_socket":            false,
+	} {
+		a, _ := parseAuth(in)
+		if a.rotatable() != want {
+			t.Fatalf("%q rotatable: got %t", in, !want)
+		}
+	}
+	if (AuthSpec{Plugins: []string{"pam"}}).rotatable() {
+		t.Fatal("unknown plugins must not be rotated")
+	}
+}
@@ -0,0 +1,405 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* ===============================
+   Batch execution
+================================= */
+
+type batchResult struct {
+	res    *CreateResult
+	err    error
+	notRun bool
+}
+
+// runBatch processes entries with up to opts.Parallel workers sharing the
+// *sql.DB pool. Results reach the reporter strictly in input order. Once
+// ctx is cancelled no new entry starts; entries in flight finish.
+func runBatch(ctx context.Context, db *sql.DB, opts Options, entries []batchEntry, fn itemFunc, rep reporter) {
+	workers := opts.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+
+	results := make([]batchResult, len(entries))
+	done := make([]chan struct{}, len(entries))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	locks := newIdentLocks()
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				e := entries[i]
+				if ctx.Err() != nil {
+					results[i] = batchResult{notRun: true}
+					close(done[i])
+					continue
+				}
+
+				unlock := locks.lock(entryIdents(opts, e.name()))
+				res, err := fn(ctx, db, e.options(opts), e.name())
+				unlock()
+
+				if interrupted(ctx, err) {
+					results[i] = batchResult{notRun: true}
+					close(done[i])
+					continue
+				}
+
+				if res != nil && e.Name != "" {
+					res.RequestedName = e.Input
+				}
+
+				if err != nil {
+					logError(opts.ErrorLogPath, fmt.Sprintf("Line %d (%s): %v", e.Line, e.Input, err))
+				}
+				results[i] = batchResult{res: res, err: err}
+				close(done[i])
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range entries {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				for k := i; k < len(entries); k++ {
+					results[k] = batchResult{notRun: true}
+					close(done[k])
+				}
+				return
+			}
+		}
+	}()
+
+	var completed, notRun []int
+	for i, e := range entries {
+		<-done[i]
+		if results[i].notRun {
+			notRun = append(notRun, e.Line)
+			rep.notRun(e.Line, e.Input)
+			continue
+		}
+		completed = append(completed, e.Line)
+		rep.report(e.Line, e.Input, results[i].res, results[i].err)
+	}
+	wg.Wait()
+
+	if len(notRun) > 0 {
+		printInterruptSummary(completed, notRun)
+	}
+}
+
+// entryIdents returns the identifiers an entry may create or drop, so
+// lines that normalize to the same name never run concurrently. Invalid
+// names are keyed by their raw input; they fail validation anyway.
+func entryIdents(opts Options, input string) []string {
+	_, name, err := resolveName(opts, input)
+	if err != nil {
+		return []string{input}
+	}
+	// The read-only companion of "x" is "x_ro", which is also what the
+	// line "x_ro" would create.
+	return []string{name, readOnlyUserName(name)}
+}
+
+// identLocks serializes work per identifier.
+type identLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newIdentLocks() *identLocks {
+	return &identLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+// lock acquires all keys in sorted order (no lock-order deadlocks) and
+// returns a function releasing them.
+func (l *identLocks) lock(keys []string) func() {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	var held []*sync.Mutex
+	for i, k := range keys {
+		if i > 0 && k == keys[i-1] {
+			continue
+		}
+		l.mu.Lock()
+		m, ok := l.locks[k]
+		if !ok {
+			m = &sync.Mutex{}
+			l.locks[k] = m
+		}
+		l.mu.Unlock()
+
+		m.Lock()
+		held = append(held, m)
+	}
+
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].Unlock()
+		}
+	}
+}
+
+/* ===============================
+   Collision pre-pass
+================================= */
+
+// batchCollision is a set of lines that would produce the same identifier.
+type batchCollision struct {
+	Ident  string
+	Lines  []int
+	Inputs []string
+}
+
+// duplicate reports whether every line has the exact same input.
+func (c batchCollision) duplicate() bool {
+	for _, in := range c.Inputs[1:] {
+		if in != c.Inputs[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c batchCollision) String() string {
+	parts := make([]string, len(c.Lines))
+	for i := range c.Lines {
+		parts[i] = fmt.Sprintf("line %d (%s)", c.Lines[i], c.Inputs[i])
+	}
+	kind := "collision"
+	if c.duplicate() {
+		kind = "duplicate"
+	}
+	return fmt.Sprintf("%s on '%s': %s", kind, c.Ident, strings.Join(parts, ", "))
+}
+
+type collisionError struct {
+	collisions []batchCollision
+}
+
+func (e *collisionError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d name collision(s) in batch file, nothing was changed", len(e.collisions))
+	for _, c := range e.collisions {
+		sb.WriteString("\n  ")
+		sb.WriteString(c.String())
+	}
+	if !e.collisionsOnlyDuplicates() {
+		sb.WriteString("\n  (use -disambiguate to add a hash suffix to colliding names)")
+	}
+	return sb.String()
+}
+
+func (e *collisionError) collisionsOnlyDuplicates() bool {
+	for _, c := range e.collisions {
+		if !c.duplicate() {
+			return false
+		}
+	}
+	return true
+}
+
+// producedIdents lists the account/database identifiers an entry would
+// create. Invalid names produce none; they fail on their own line.
+func producedIdents(opts Options, e batchEntry) []string {
+	_, name, err := resolveName(opts, e.name())
+	if err != nil {
+		return nil
+	}
+	if opts.ReadOnlyUser && opts.Command == cmdCreate {
+		return []string{name, readOnlyUserName(name)}
+	}
+	return []string{name}
+}
+
+// findBatchCollisions returns every identifier claimed by more than one
+// entry, ordered by first line.
+func findBatchCollisions(opts Options, entries []batchEntry) []batchCollision {
+	owners := make(map[string][]int)
+	var order []string
+	for i, e := range entries {
+		for _, id := range producedIdents(opts, e) {
+			if _, ok := owners[id]; !ok {
+				order = append(order, id)
+			}
+			owners[id] = append(owners[id], i)
+		}
+	}
+
+	var out []batchCollision
+	seen := make(map[string]bool) // same entries colliding on name and name_ro
+	for _, id := range order {
+		idx := owners[id]
+		if len(idx) < 2 {
+			continue
+		}
+		key := fmt.Sprint(idx)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		c := batchCollision{Ident: id}
+		for _, i := range idx {
+			c.Lines = append(c.Lines, entries[i].Line)
+			c.Inputs = append(c.Inputs, entries[i].Input)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// checkBatchCollisions fails on any collision, unless -disambiguate is set
+// for create: then the first line keeps its name, exact duplicates are
+// dropped and other colliding lines get a hash suffix.
+func checkBatchCollisions(opts Options, entries []batchEntry) ([]batchEntry, error) {
+	collisions := findBatchCollisions(opts, entries)
+	if len(collisions) == 0 {
+		return entries, nil
+	}
+	if !opts.Disambiguate || opts.Command != cmdCreate {
+		return nil, &collisionError{collisions: collisions}
+	}
+
+	byLine := make(map[int]int, len(entries))
+	for i, e := range entries {
+		byLine[e.Line] = i
+	}
+
+	drop := make(map[int]bool)
+	for _, c := range collisions {
+		first := entries[byLine[c.Lines[0]]]
+		for _, line := range c.Lines[1:] {
+			i := byLine[line]
+			if drop[i] || entries[i].Name != "" {
+				continue
+			}
+			if entries[i].Input == first.Input {
+				drop[i] = true
+				fmt.Fprintf(os.Stderr, "⚠️  Line %d (%s): duplicate of line %d, ignored\n",
+					line, entries[i].Input, first.Line)
+				continue
+			}
+			_, name, _ := resolveName(opts, entries[i].Input)
+			entries[i].Name = hashSuffixed(name, entries[i].Input)
+			fmt.Fprintf(os.Stderr, "⚠️  Line %d (%s): collides with line %d, using '%s'\n",
+				line, entries[i].Input, first.Line, entries[i].Name)
+		}
+	}
+
+	var kept []batchEntry
+	for i, e := range entries {
+		if !drop[i] {
+			kept = append(kept, e)
+		}
+	}
+
+	// A suffix could, in theory, hit another line's name.
+	if again := findBatchCollisions(opts, kept); len(again) > 0 {
+		return nil, &collisionError{collisions: again}
+	}
+	return kept, nil
+}
+
+// batchCharset is one distinct charset/collation pair in a batch file
+// and the lines that use it.
+type batchCharset struct {
+	Charset   string
+	Collation string
+	Lines     []int
+}
+
+// batchCharsets groups the entries of a create batch by the charset and
+// collation they would get, in order of first use. Entries with neither
+// are left out.
+func batchCharsets(opts Options, entries []batchEntry) []batchCharset {
+	var out []batchCharset
+	index := make(map[[2]string]int)
+	for _, e := range entries {
+		o := e.options(opts)
+		key := [2]string{o.Charset, o.Collation}
+		if key == ([2]string{}) {
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, batchCharset{Charset: o.Charset, Collation: o.Collation})
+		}
+		out[i].Lines = append(out[i].Lines, e.Line)
+	}
+	return out
+}
+
+// checkBatchCharsets checks every charset/collation of a create batch
+// against the server before anything runs, so a bad collation= on a late
+// line cannot fail after the earlier lines were created.
+func checkBatchCharsets(ctx context.Context, db *sql.DB, opts Options, entries []batchEntry) error {
+	if opts.Command != cmdCreate {
+		return nil
+	}
+	sets := batchCharsets(opts, entries)
+	if len(sets) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	var problems []string
+	for _, c := range sets {
+		reason, err := checkCharsetCollation(ctx, db, c.Charset, c.Collation)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			lines := make([]string, len(c.Lines))
+			for i, l := range c.Lines {
+				lines[i] = fmt.Sprint(l)
+			}
+			problems = append(problems, fmt.Sprintf("%s (line %s)", reason, strings.Join(lines, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d invalid character set/collation(s) in batch file, nothing was changed\n  %s",
+			len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
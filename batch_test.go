@@ -0,0 +1,255 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingReporter struct {
+	lines   []int
+	skipped []int
+	sum     BatchSummary
+}
+
+func (r *recordingReporter) report(line int, input string, res *CreateResult, err error) {
+	r.sum.add(res, err)
+	r.lines = append(r.lines, line)
+}
+func (r *recordingReporter) notRun(line int, _ string) {
+	r.sum.Total++
+	r.sum.NotRun++
+	r.skipped = append(r.skipped, line)
+}
+func (r *recordingReporter) previous(_ int, _ string, status string) {
+	r.sum.addPrevious(status)
+}
+func (r *recordingReporter) finish() error         { return nil }
+func (r *recordingReporter) summary() BatchSummary { return r.sum }
+
+func TestRunBatchOrderAndIdentLocking(t *testing.T) {
+	inputs := []string{"a.se", "b.se", "A-se", "c.se", "a_se", "d.se", "e.se", "f.se"}
+	var entries []batchEntry
+	for i, in := range inputs {
+		entries = append(entries, batchEntry{Line: i + 1, Input: in})
+	}
+
+	var mu sync.Mutex
+	active := make(map[string]int)
+	var overlap bool
+
+	fn := func(_ context.Context, _ *sql.DB, opts Options, input string) (*CreateResult, error) {
+		_, name, err := resolveName(opts, input)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		active[name]++
+		if active[name] > 1 {
+			overlap = true
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		active[name]--
+		mu.Unlock()
+		if name == "d_se" {
+			return nil, fmt.Errorf("boom")
+		}
+		return &CreateResult{Status: StatusCreated, Name: name}, nil
+	}
+
+	rep := &recordingReporter{}
+	runBatch(context.Background(), nil, Options{Normalize: true, Parallel: 4}, entries, fn, rep)
+
+	if overlap {
+		t.Fatal("entries with the same normalized name ran concurrently")
+	}
+	for i, line := range rep.lines {
+		if line != i+1 {
+			t.Fatalf("results out of order: %v", rep.lines)
+		}
+	}
+	if s := rep.summary(); s.Total != len(inputs) || s.Failed != 1 || s.Created != len(inputs)-1 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
+
+func TestRunBatchInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fn := func(ctx context.Context, _ *sql.DB, _ Options, input string) (*CreateResult, error) {
+		switch input {
+		case "c":
+			// The signal arrives while this line is in flight; it still
+			// finishes.
+			cancel()
+		case "d":
+			// Interrupted during its checks, before changing anything.
+			return nil, ctx.Err()
+		}
+		return &CreateResult{Status: StatusCreated, Name: input}, nil
+	}
+
+	rep := &recordingReporter{}
+	runBatch(ctx, nil, Options{Parallel: 1}, entriesOf("a", "b", "c", "d", "e", "f"), fn, rep)
+
+	if fmt.Sprint(rep.lines) != "[1 2 3]" || fmt.Sprint(rep.skipped) != "[4 5 6]" {
+		t.Fatalf("reported %v, not run %v", rep.lines, rep.skipped)
+	}
+	if s := rep.summary(); s.Total != 6 || s.Created != 3 || s.NotRun != 3 || s.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	cases := map[string][]int{
+		"":             nil,
+		"4":            {4},
+		"1-3, 7, 9-10": {1, 2, 3, 7, 9, 10},
+	}
+	for want, lines := range cases {
+		if got := lineRanges(lines); got != want {
+			t.Fatalf("lineRanges(%v) = %q, want %q", lines, got, want)
+		}
+	}
+}
+
+func entriesOf(inputs ...string) []batchEntry {
+	var entries []batchEntry
+	for i, in := range inputs {
+		entries = append(entries, batchEntry{Line: i + 1, Input: in})
+	}
+	return entries
+}
+
+func TestFindBatchCollisions(t *testing.T) {
+	opts := Options{Normalize: true, Command: cmdCreate}
+	entries := entriesOf("my-site.se", "other.se", "my.site.se", "My_Site.se", "other.se", "bad name!")
+
+	got := findBatchCollisions(opts, entries)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 collisions, got %d: %v", len(got), got)
+	}
+	if got[0].Ident != "my_site_se" || fmt.Sprint(got[0].Lines) != "[1 3 4]" || got[0].duplicate() {
+		t.Fatalf("unexpected first collision: %+v", got[0])
+	}
+	if got[1].Ident != "other_se" || !got[1].duplicate() {
+		t.Fatalf("unexpected second collision: %+v", got[1])
+	}
+
+	// The read-only companion of "shop" is "shop_ro".
+	opts.ReadOnlyUser = true
+	got = findBatchCollisions(opts, entriesOf("shop", "shop_ro"))
+	if len(got) != 1 || got[0].Ident != "shop_ro" {
+		t.Fatalf("expected companion collision, got %v", got)
+	}
+}
+
+func TestCheckBatchCollisions(t *testing.T) {
+	opts := Options{Normalize: true, Command: cmdCreate}
+	entries := entriesOf("my-site.se", "my.site.se", "my-site.se", "x.se")
+
+	if _, err := checkBatchCollisions(opts, entries); err == nil {
+		t.Fatal("expected collision error without -disambiguate")
+	}
+
+	opts.Disambiguate = true
+	kept, err := checkBatchCollisions(opts, entriesOf("my-site.se", "my.site.se", "my-site.se", "x.se"))
+	if err != nil {
+		t.Fatalf("disambiguate: %v", err)
+	}
+	if len(kept) != 3 {
+		t.Fatalf("expected duplicate dropped, got %+v", kept)
+	}
+	if kept[0].name() != "my-site.se" || kept[2].name() != "x.se" {
+		t.Fatalf("non-colliding names must be untouched: %+v", kept)
+	}
+	renamed := kept[1].name()
+	if renamed == "my.site.se" || len(renamed) != len("my_site_se_")+8 {
+		t.Fatalf("expected hash-suffixed name, got %q", renamed)
+	}
+	if err := validateIdentifier(renamed); err != nil {
+		t.Fatalf("suffixed name should validate: %v", err)
+	}
+
+	opts.Command = cmdDelete
+	if _, err := checkBatchCollisions(opts, entriesOf("a.se", "a-se")); err == nil {
+		t.Fatal("-disambiguate must not apply to delete")
+	}
+}
+
+func TestParseBatchLine(t *testing.T) {
+	e, err := parseBatchLine(3, "legacy.se  charset=latin1 collation=LATIN1_swedish_ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Line != 3 || e.Input != "legacy.se" || e.Charset != "latin1" || e.Collation != "latin1_swedish_ci" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	for _, raw := range []string{"a.se owner=x", "a.se charset=latin1;drop", "a.se collation="} {
+		if _, err := parseBatchLine(1, raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestBatchEntryOptions(t *testing.T) {
+	opts := Options{Charset: "utf8mb4", Collation: "utf8mb4_uca1400_ai_ci"}
+
+	o := batchEntry{Input: "a.se"}.options(opts)
+	if o.Charset != "utf8mb4" || o.Collation != "utf8mb4_uca1400_ai_ci" {
+		t.Fatalf("defaults should apply: %+v", o)
+	}
+	o = batchEntry{Input: "a.se", Charset: "latin1"}.options(opts)
+	if o.Charset != "latin1" || o.Collation != "" {
+		t.Fatalf("a line override should replace both: %+v", o)
+	}
+	if createDatabaseSQL("a_se", o.Charset, "latin1_swedish_ci") != "CREATE DATABASE `a_se` CHARACTER SET latin1 COLLATE latin1_swedish_ci" {
+		t.Fatal("unexpected CREATE DATABASE")
+	}
+}
+
+func TestBatchCharsets(t *testing.T) {
+	opts := Options{Charset: "utf8mb4"}
+	entries := []batchEntry{
+		{Line: 1, Input: "a.se"},
+		{Line: 2, Input: "b.se", Charset: "latin1"},
+		{Line: 3, Input: "c.se"},
+		{Line: 300, Input: "d.se", Collation: "utf8mb4_nope"},
+	}
+	got := batchCharsets(opts, entries)
+	want := []batchCharset{
+		{Charset: "utf8mb4", Lines: []int{1, 3}},
+		{Charset: "latin1", Lines: []int{2}},
+		{Collation: "utf8mb4_nope", Lines: []int{300}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if got := batchCharsets(Options{}, entries[:1]); got != nil {
+		t.Fatalf("no charset anywhere: %+v", got)
+	}
+}
@@ -0,0 +1,208 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/* ===============================
+   Checkpoints
+================================= */
+
+// A checkpoint records the outcome of every finished batch line, so
+// -resume can skip the lines an earlier run of the same file (same
+// content, command and server) already completed. Failed lines are
+// retried. Passwords are never stored.
+type checkpointHeader struct {
+	File    string    `json:"file"`
+	SHA256  string    `json:"sha256"`
+	Command string    `json:"command"`
+	Server  string    `json:"server"`
+	Started time.Time `json:"started"`
+}
+
+type checkpointLine struct {
+	Line   int    `json:"line"`
+	Input  string `json:"input"`
+	Status string `json:"status"`
+}
+
+const checkpointFailed = "failed"
+
+type checkpoint struct {
+	path   string
+	header checkpointHeader
+	// failed is set once a write fails; the run goes on without it.
+	failed bool
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkpointPath keys the checkpoint by server, command and file hash.
+func checkpointPath(dir string, h checkpointHeader) string {
+	key := sha256.Sum256([]byte(h.Server + "\n" + h.Command + "\n" + h.SHA256))
+	return filepath.Join(dir, h.Command+"-"+hex.EncodeToString(key[:8])+".jsonl")
+}
+
+// parseCheckpoint returns the header and the last recorded status per
+// line. A torn last line is ignored, as in the journal.
+func parseCheckpoint(r io.Reader) (checkpointHeader, map[int]checkpointLine, error) {
+	var h checkpointHeader
+	lines := make(map[int]checkpointLine)
+
+	sc := bufio.NewScanner(r)
+	first := true
+	var pendingErr error
+	for sc.Scan() {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if pendingErr != nil {
+			return h, nil, pendingErr
+		}
+		if first {
+			if err := json.Unmarshal([]byte(text), &h); err != nil || h.SHA256 == "" {
+				return h, nil, errors.New("invalid checkpoint header")
+			}
+			first = false
+			continue
+		}
+		var l checkpointLine
+		if err := json.Unmarshal([]byte(text), &l); err != nil {
+			pendingErr = fmt.Errorf("invalid checkpoint line: %w", err)
+			continue
+		}
+		lines[l.Line] = l
+	}
+	if first {
+		return h, nil, errors.New("empty checkpoint")
+	}
+	return h, lines, sc.Err()
+}
+
+// openCheckpoint starts the checkpoint for a batch run. Without -resume
+// it starts over; with -resume it returns the lines already completed.
+func openCheckpoint(opts Options, file string) (*checkpoint, map[int]checkpointLine, error) {
+	sum, err := fileSHA256(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := checkpointHeader{
+		File:    file,
+		SHA256:  sum,
+		Command: opts.Command,
+		Server:  serverSection(opts.Server),
+		Started: time.Now().Truncate(time.Second),
+	}
+	cp := &checkpoint{path: checkpointPath(opts.CheckpointDir, h), header: h}
+
+	if opts.Resume {
+		f, err := os.Open(cp.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			fmt.Fprintf(os.Stderr, "No checkpoint for %s; processing every line\n", file)
+		case err != nil:
+			return nil, nil, err
+		default:
+			defer f.Close()
+			prev, lines, err := parseCheckpoint(f)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", cp.path, err)
+			}
+			cp.header = prev
+			done := make(map[int]checkpointLine)
+			for n, l := range lines {
+				if l.Status != checkpointFailed {
+					done[n] = l
+				}
+			}
+			return cp, done, nil
+		}
+	}
+
+	if opts.DryRun {
+		return nil, nil, nil
+	}
+	if err := os.Remove(cp.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	if err := appendJSONLine(cp.path, h); err != nil {
+		return nil, nil, err
+	}
+	return cp, nil, nil
+}
+
+func (cp *checkpoint) record(opts Options, line int, input string, res *CreateResult, err error) {
+	if cp.failed {
+		return
+	}
+	status := checkpointFailed
+	if err == nil {
+		status = res.Status.String()
+	}
+	if werr := appendJSONLine(cp.path, checkpointLine{Line: line, Input: input, Status: status}); werr != nil {
+		cp.failed = true
+		fmt.Fprintf(os.Stderr, "⚠️  Checkpoint not updated (%s): %v; -resume will redo later lines\n", cp.path, werr)
+		logError(opts.ErrorLogPath, fmt.Sprintf("Checkpoint write failed (%s): %v", cp.path, werr))
+	}
+}
+
+// checkpointReporter records every line before passing it on. Dry runs
+// change nothing, so they are not recorded.
+type checkpointReporter struct {
+	reporter
+	cp   *checkpoint
+	opts Options
+}
+
+func (r *checkpointReporter) report(line int, input string, res *CreateResult, err error) {
+	if !r.opts.DryRun {
+		r.cp.record(r.opts, line, input, res, err)
+	}
+	r.reporter.report(line, input, res, err)
+}
+
+// finish drops the checkpoint once every line has been done.
+func (r *checkpointReporter) finish() error {
+	s := r.summary()
+	if !r.opts.DryRun && !r.cp.failed && s.Failed == 0 && s.NotRun == 0 {
+		if err := os.Remove(r.cp.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logError(r.opts.ErrorLogPath, fmt.Sprintf("Checkpoint cleanup failed (%s): %v", r.cp.path, err))
+		}
+	}
+	return r.reporter.finish()
+}
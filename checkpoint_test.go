@@ -0,0 +1,100 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "list.txt")
+	if err := os.WriteFile(file, []byte("a.se\nb.se\nc.se\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Command: cmdCreate, CheckpointDir: filepath.Join(dir, "cp")}
+
+	cp, done, err := openCheckpoint(opts, file)
+	if err != nil || cp == nil || done != nil {
+		t.Fatalf("first run: %v %v %v", cp, done, err)
+	}
+	rep := &checkpointReporter{reporter: &recordingReporter{}, cp: cp, opts: opts}
+	rep.report(1, "a.se", &CreateResult{Status: StatusCreated}, nil)
+	rep.report(2, "b.se", nil, errors.New("timeout"))
+	rep.notRun(3, "c.se")
+	_ = rep.finish()
+
+	if _, err := os.Stat(cp.path); err != nil {
+		t.Fatalf("checkpoint should be kept after failures: %v", err)
+	}
+
+	// Same file, other server: a different checkpoint.
+	other := opts
+	other.Server, other.Resume = "prod", true
+	if _, done, err := openCheckpoint(other, file); err != nil || len(done) != 0 {
+		t.Fatalf("other server: %v %v", done, err)
+	}
+
+	opts.Resume = true
+	cp2, done, err := openCheckpoint(opts, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[1].Status != "created" || done[1].Input != "a.se" {
+		t.Fatalf("only line 1 should count as done: %+v", done)
+	}
+
+	rep = &checkpointReporter{reporter: &recordingReporter{}, cp: cp2, opts: opts}
+	rep.previous(1, "a.se", done[1].Status)
+	rep.report(2, "b.se", &CreateResult{Status: StatusCreated}, nil)
+	rep.report(3, "c.se", &CreateResult{Status: StatusSkipped}, nil)
+	s := rep.summary()
+	if s.Total != 3 || s.Created != 1 || s.CreatedPreviously != 1 || s.Skipped != 1 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	_ = rep.finish()
+	if _, err := os.Stat(cp2.path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("checkpoint should be removed once every line is done: %v", err)
+	}
+}
+
+func TestCheckpointChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "list.txt")
+	_ = os.WriteFile(file, []byte("a.se\n"), 0644)
+	opts := Options{Command: cmdCreate, CheckpointDir: dir}
+
+	cp, _, err := openCheckpoint(opts, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.record(opts, 1, "a.se", &CreateResult{Status: StatusCreated}, nil)
+
+	_ = os.WriteFile(file, []byte("a.se\nb.se\n"), 0644)
+	opts.Resume = true
+	if _, done, err := openCheckpoint(opts, file); err != nil || len(done) != 0 {
+		t.Fatalf("an edited file must not resume from the old checkpoint: %v %v", done, err)
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	s := BatchSummary{Total: 10, Created: 3, Previous: 5, CreatedPreviously: 4, Failed: 2}
+	want := "Summary: 10 lines: 3 created, 4 created previously, 1 done previously, 2 failed"
+	if got := summaryLine(s); got != want {
+		t.Fatalf("got %q", got)
+	}
+}
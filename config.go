@@ -19,15 +19,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"golang.org/x/term"
 )
 
 const appName = "mariadb-tool"
 
 type DefaultPaths struct {
-	ConfigPath string
-	ErrorLog   string
-	CSVPath    string
+	ConfigPath  string
+	ErrorLog    string
+	CSVPath     string
+	Journal     string
+	Checkpoints string
 }
 
 func xdgDir(envVar string, fallbackParts ...string) (string, error) {
@@ -57,9 +63,11 @@ func defaultPaths() (DefaultPaths, error) {
 	}
 
 	return DefaultPaths{
-		ConfigPath: filepath.Join(cfgHome, appName, "config.ini"),
-		CSVPath:    filepath.Join(dataHome, appName, "accounts.csv"),
-		ErrorLog:   filepath.Join(stateHome, appName, "error.log"),
+		ConfigPath:  filepath.Join(cfgHome, appName, "config.ini"),
+		CSVPath:     filepath.Join(dataHome, appName, "accounts.csv"),
+		ErrorLog:    filepath.Join(stateHome, appName, "error.log"),
+		Journal:     filepath.Join(stateHome, appName, "journal.jsonl"),
+		Checkpoints: filepath.Join(stateHome, appName, "checkpoints"),
 	}, nil
 }
 
@@ -73,8 +81,10 @@ func configFileExists(path string) bool {
 	return err == nil
 }
 
-func loadConfig(filename, section string) (map[string]string, error) {
-	config := make(map[string]string)
+// parseINI reads every section of an INI file. Section names are
+// lower-cased; keys and values are kept as written.
+func parseINI(filename string) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
 
 	file, err := os.Open(filename)
 	if err != nil {
@@ -82,8 +92,7 @@ func loadConfig(filename, section string) (map[string]string, error) {
 	}
 	defer file.Close()
 
-	wantSection := "[" + section + "]"
-	inSection := false
+	var current map[string]string
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -93,10 +102,14 @@ func loadConfig(filename, section string) (map[string]string, error) {
 		}
 
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			inSection = strings.EqualFold(line, wantSection)
+			name := strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if sections[name] == nil {
+				sections[name] = make(map[string]string)
+			}
+			current = sections[name]
 			continue
 		}
-		if !inSection {
+		if current == nil {
 			continue
 		}
 
@@ -107,72 +120,284 @@ func loadConfig(filename, section string) (map[string]string, error) {
 		k := strings.TrimSpace(parts[0])
 		v := strings.TrimSpace(parts[1])
 		if k != "" {
-			config[k] = v
+			current[k] = v
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	return sections, nil
+}
+
+func loadConfig(filename, section string) (map[string]string, error) {
+	sections, err := parseINI(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := sections[strings.ToLower(section)]
 	if len(config) == 0 {
 		return nil, fmt.Errorf("missing or empty section [%s] in %s", section, filename)
 	}
 	return config, nil
 }
 
-func initializeConfig(path string) error {
+// loadConnectionConfig merges the option files with the selected section
+// of config.ini. The default [mariadb] section may be absent when the option
+// files already provide credentials; named servers must be defined.
+func loadConnectionConfig(filename, section string, optionCfg map[string]string) (map[string]string, error) {
+	if section == serverSectionBase && optionCfg["username"] != "" &&
+		!configSectionExists(filename, section) {
+		return mergeConnectionConfig(optionCfg, nil), nil
+	}
+
+	toolCfg, err := loadConfig(filename, section)
+	if err != nil {
+		return nil, err
+	}
+	return mergeConnectionConfig(optionCfg, toolCfg), nil
+}
+
+/* ===============================
+   Server profiles
+================================= */
+
+// The default server is [mariadb]; named servers live in [mariadb:<name>].
+const serverSectionBase = "mariadb"
+
+func serverSection(server string) string {
+	server = strings.ToLower(strings.TrimSpace(server))
+	if server == "" || server == "default" {
+		return serverSectionBase
+	}
+	return serverSectionBase + ":" + server
+}
+
+// serverNames lists the server profiles defined in the parsed config.
+// The plain [mariadb] section is reported as "default".
+func serverNames(sections map[string]map[string]string) []string {
+	var names []string
+	for sec := range sections {
+		switch {
+		case sec == serverSectionBase:
+			names = append(names, "default")
+		case strings.HasPrefix(sec, serverSectionBase+":"):
+			names = append(names, strings.TrimPrefix(sec, serverSectionBase+":"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyServerDefaults lets a server section set user-host (possibly a
+// list), timeout, csv, charset and collation.
+// Flags given explicitly on the command line always win.
+func applyServerDefaults(opts *Options, cfg map[string]string, explicit map[string]bool) error {
+	if v := cfg["user-host"]; v != "" && !explicit["user-host"] {
+		opts.UserHost = v
+	}
+	if v := cfg["timeout"]; v != "" && !explicit["timeout"] {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout '%s' in config", v)
+		}
+		opts.Timeout = d
+	}
+	if v := cfg["csv"]; v != "" && !explicit["csv"] {
+		opts.CSVPath = v
+	}
+	// Either flag replaces both config values, as a batch line does.
+	if !explicit["charset"] && !explicit["collation"] {
+		if v := strings.ToLower(cfg["charset"]); v != "" {
+			if err := validateCharset(v); err != nil {
+				return err
+			}
+			opts.Charset = v
+		}
+		if v := strings.ToLower(cfg["collation"]); v != "" {
+			if err := validateCollation(v); err != nil {
+				return err
+			}
+			opts.Collation = v
+		}
+	}
+	return nil
+}
+
+// writeConfigSection replaces (or appends) one section of an INI file and
+// leaves every other section untouched.
+func writeConfigSection(path, section string, values [][2]string) error {
+	var lines []string
+	if data, err := os.ReadFile(path); err == nil {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	body := []string{"[" + section + "]"}
+	for _, kv := range values {
+		body = append(body, kv[0]+"="+kv[1])
+	}
+
+	var out []string
+	inserted, skipping := false, false
+	for _, line := range lines {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+			skipping = strings.EqualFold(strings.TrimSpace(t[1:len(t)-1]), section)
+			if skipping && !inserted {
+				out = append(out, body...)
+				out = append(out, "")
+				inserted = true
+			}
+		}
+		if !skipping {
+			out = append(out, line)
+		}
+	}
+	if !inserted {
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, body...)
+	}
+
+	content := strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+
+	// 0600 because it contains creds
+	return os.WriteFile(path, []byte(content), 0600)
+}
+
+func configSectionExists(path, section string) bool {
+	sections, err := parseINI(path)
+	if err != nil {
+		return false
+	}
+	_, ok := sections[strings.ToLower(section)]
+	return ok
+}
+
+// Environment variables read by non-interactive init (-i -non-interactive).
+const (
+	envInitUser     = "MARIADB_TOOL_USER"
+	envInitPassword = "MARIADB_TOOL_PASSWORD"
+	envInitHost     = "MARIADB_TOOL_HOST"
+	envInitPort     = "MARIADB_TOOL_PORT"
+)
+
+func initializeConfig(opts Options, section string) error {
+	path := opts.ConfigPath
 	if err := ensureParentDir(path, 0700); err != nil {
 		return err
 	}
 
-	// If exists: ask before overwrite (simple CLI confirm)
-	if configFileExists(path) {
-		fmt.Printf("%s already exists. Overwrite? (y/N): ", path)
-		var confirm string
-		fmt.Scanln(&confirm)
-		if strings.ToLower(strings.TrimSpace(confirm)) != "y" {
+	in := bufio.NewReader(os.Stdin)
+
+	// If the section exists: ask before overwrite (simple CLI confirm).
+	// Other sections are always preserved.
+	if configSectionExists(path, section) && !opts.Yes {
+		if opts.NonInteractive {
+			return fmt.Errorf("[%s] already exists in %s (use -yes to overwrite)", section, path)
+		}
+		fmt.Printf("[%s] already exists in %s. Overwrite? (y/N): ", section, path)
+		if strings.ToLower(readLine(in)) != "y" {
 			return nil
 		}
 	}
 
 	var user, pass, host, port string
-	fmt.Print("Enter MariaDB root username [root]: ")
-	fmt.Scanln(&user)
-	if strings.TrimSpace(user) == "" {
-		user = "root"
-	}
+	if opts.NonInteractive {
+		user, pass, host, port = nonInteractiveInit(opts, in, os.Getenv)
+	} else {
+		fmt.Print("Enter MariaDB root username [root]: ")
+		user = firstNonEmpty(readLine(in), "root")
+
+		p, err := readSecret(in, "Enter MariaDB root password: ")
+		if err != nil {
+			return err
+		}
+		pass = p
 
-	fmt.Print("Enter MariaDB root password: ")
-	fmt.Scanln(&pass)
+		fmt.Print("Enter MariaDB hostname [localhost]: ")
+		host = firstNonEmpty(readLine(in), "localhost")
 
-	fmt.Print("Enter MariaDB hostname [localhost]: ")
-	fmt.Scanln(&host)
-	if strings.TrimSpace(host) == "" {
-		host = "localhost"
+		fmt.Print("Enter MariaDB port [3306]: ")
+		port = firstNonEmpty(readLine(in), "3306")
 	}
 
-	fmt.Print("Enter MariaDB port [3306]: ")
-	fmt.Scanln(&port)
-	if strings.TrimSpace(port) == "" {
-		port = "3306"
+	values := [][2]string{
+		{"username", user},
+		{"password", pass},
+		{"hostname", host},
+		{"port", port},
 	}
 
-	content := fmt.Sprintf(
-		"[mariadb]\nusername=%s\npassword=%s\nhostname=%s\nport=%s\n",
-		user, pass, host, port,
-	)
+	// Never write credentials that do not work.
+	if !opts.InitNoVerify {
+		cfg := make(map[string]string, len(values))
+		for _, kv := range values {
+			cfg[kv[0]] = kv[1]
+		}
+		db, err := openDB(cfg, opts.Timeout)
+		if err != nil {
+			return fmt.Errorf("connection test failed (nothing written): %w", err)
+		}
+		_ = db.Close()
+	}
 
-	// 0600 because it contains creds
-	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+	if err := writeConfigSection(path, section, values); err != nil {
 		return err
 	}
 
-	fmt.Printf("✅ %s created (0600).\n", path)
+	fmt.Printf("✅ [%s] written to %s (0600).\n", section, path)
 	return nil
 }
 
+// nonInteractiveInit resolves the credentials for -i -non-interactive:
+// flags first, then MARIADB_TOOL_*, then the defaults. The password comes
+// from stdin with -init-password-stdin, otherwise from the environment.
+func nonInteractiveInit(opts Options, in *bufio.Reader, getenv func(string) string) (user, pass, host, port string) {
+	user = firstNonEmpty(opts.InitUser, getenv(envInitUser), "root")
+	host = firstNonEmpty(opts.InitHost, getenv(envInitHost), "localhost")
+	port = firstNonEmpty(opts.InitPort, getenv(envInitPort), "3306")
+	pass = getenv(envInitPassword)
+	if opts.InitPasswordStdin {
+		pass = readLine(in)
+	}
+	return user, pass, host, port
+}
+
+// readLine reads one line without the trailing newline. Unlike fmt.Scanln
+// it keeps inner spaces, which passwords may contain.
+func readLine(in *bufio.Reader) string {
+	line, _ := in.ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
+
+// readSecret reads a password without echo when stdin is a terminal.
+func readSecret(in *bufio.Reader, prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(b), err
+	}
+	return readLine(in), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 func validateNotEmptyPaths(p DefaultPaths) error {
-	if p.ConfigPath == "" || p.ErrorLog == "" || p.CSVPath == "" {
+	if p.ConfigPath == "" || p.ErrorLog == "" || p.CSVPath == "" || p.Journal == "" || p.Checkpoints == "" {
 		return errors.New("internal error: empty default paths")
 	}
 	return nil
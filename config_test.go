@@ -0,0 +1,138 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteConfigSectionPreservesOthers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.ini")
+	initial := "[mariadb]\nusername=root\nhostname=db1\n\n[profile:reporting]\nprivileges=SELECT\n"
+	if err := os.WriteFile(path, []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeConfigSection(path, "mariadb:staging", [][2]string{{"username", "admin"}, {"hostname", "stage"}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := writeConfigSection(path, "mariadb", [][2]string{{"username", "admin"}, {"hostname", "db2"}}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	sections, err := parseINI(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sections["mariadb"]["hostname"]; got != "db2" {
+		t.Fatalf("[mariadb] hostname=%q, want db2", got)
+	}
+	if got := sections["mariadb:staging"]["hostname"]; got != "stage" {
+		t.Fatalf("[mariadb:staging] hostname=%q, want stage", got)
+	}
+	if got := sections["profile:reporting"]["privileges"]; got != "SELECT" {
+		t.Fatalf("[profile:reporting] lost: %q", got)
+	}
+
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "[mariadb]"); n != 1 {
+		t.Fatalf("expected one [mariadb] section, got %d:\n%s", n, data)
+	}
+}
+
+func TestServerNames(t *testing.T) {
+	sections := map[string]map[string]string{
+		"mariadb":           {},
+		"mariadb:prod":      {},
+		"mariadb:staging":   {},
+		"profile:reporting": {},
+	}
+	got := strings.Join(serverNames(sections), ",")
+	if got != "default,prod,staging" {
+		t.Fatalf("serverNames=%q", got)
+	}
+	if serverSection("") != "mariadb" || serverSection("default") != "mariadb" || serverSection("Prod") != "mariadb:prod" {
+		t.Fatal("unexpected serverSection mapping")
+	}
+}
+
+func TestApplyServerDefaults(t *testing.T) {
+	cfg := map[string]string{"user-host": "10.0.0.5", "timeout": "15s", "csv": "/tmp/x.csv"}
+
+	opts := Options{UserHost: "localhost", Timeout: defaultTimeout, CSVPath: "a.csv"}
+	if err := applyServerDefaults(&opts, cfg, map[string]bool{"csv": true}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.UserHost != "10.0.0.5" || opts.Timeout != 15*time.Second || opts.CSVPath != "a.csv" {
+		t.Fatalf("unexpected opts: %+v", opts)
+	}
+
+	if err := applyServerDefaults(&opts, map[string]string{"timeout": "soon"}, nil); err == nil {
+		t.Fatal("expected error for invalid timeout")
+	}
+
+	cfg = map[string]string{"charset": "Latin1", "collation": "latin1_swedish_ci"}
+	opts = Options{}
+	if err := applyServerDefaults(&opts, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Charset != "latin1" || opts.Collation != "latin1_swedish_ci" {
+		t.Fatalf("unexpected charset defaults: %+v", opts)
+	}
+	opts = Options{Collation: "utf8mb4_bin"}
+	_ = applyServerDefaults(&opts, cfg, map[string]bool{"collation": true})
+	if opts.Charset != "" || opts.Collation != "utf8mb4_bin" {
+		t.Fatalf("-collation should replace both config values: %+v", opts)
+	}
+	if err := applyServerDefaults(&opts, map[string]string{"collation": "x'y"}, nil); err == nil {
+		t.Fatal("expected error for invalid collation")
+	}
+}
+
+func TestNonInteractiveInit(t *testing.T) {
+	env := map[string]string{
+		envInitUser:     "envadmin",
+		envInitPassword: "envpass",
+		envInitHost:     "db.env",
+		envInitPort:     "3307",
+	}
+	cases := []struct {
+		name  string
+		opts  Options
+		env   map[string]string
+		stdin string
+		want  [4]string // user, pass, host, port
+	}{
+		{"defaults", Options{}, nil, "", [4]string{"root", "", "localhost", "3306"}},
+		{"env", Options{}, env, "", [4]string{"envadmin", "envpass", "db.env", "3307"}},
+		{"flags win", Options{InitUser: "admin", InitHost: "db.flag", InitPort: "3308"}, env, "",
+			[4]string{"admin", "envpass", "db.flag", "3308"}},
+		{"stdin password", Options{InitPasswordStdin: true}, env, "pass with spaces\n",
+			[4]string{"envadmin", "pass with spaces", "db.env", "3307"}},
+		{"blank env ignored", Options{}, map[string]string{envInitUser: "  "}, "",
+			[4]string{"root", "", "localhost", "3306"}},
+	}
+	for _, c := range cases {
+		getenv := func(k string) string { return c.env[k] }
+		u, p, h, port := nonInteractiveInit(c.opts, bufio.NewReader(strings.NewReader(c.stdin)), getenv)
+		if got := [4]string{u, p, h, port}; got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
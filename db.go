@@ -17,23 +17,40 @@ import (
 	"bufio"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/bits"
+	"net"
+	"net/netip"
 	"os"
 	"regexp"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 var defaultTimeout = 6 * time.Second
 
 type Options struct {
+	Command           string
+	Target            string
 	CreateName        string
+	DeleteName        string
 	FileList          string
 	Init              bool
+	NonInteractive    bool
+	InitUser          string
+	InitHost          string
+	InitPort          string
+	InitPasswordStdin bool
+	InitNoVerify      bool
 	ConfigPath        string
+	DefaultsFile      string
+	NoDefaults        bool
+	Server            string
 	UserHost          string
 	AllowWildcardHost bool
 	Timeout           time.Duration
@@ -42,6 +59,26 @@ type Options struct {
 	ErrorLogPath      string
 	DryRun            bool
 	Normalize         bool
+	Yes               bool
+	Output            string
+	Parallel          int
+	Disambiguate      bool
+	Profile           string
+	Privileges        PrivilegeProfile
+	ReadOnlyUser      bool
+	PlanOut           string
+	Charset           string
+	Collation         string
+	Auth              AuthSpec
+	PasswordPolicy    PasswordPolicy
+	Manifest          string
+	Apply             bool
+	Prune             bool
+	Owner             string
+	Ticket            string
+	JournalPath       string
+	Resume            bool
+	CheckpointDir     string
 }
 
 type CreateStatus int
@@ -51,33 +88,69 @@ const (
 	StatusSkipped
 	StatusDryRun
 	StatusCreated
+	StatusDeleted
+	StatusRotated
+	StatusAdopted
 )
 
 type CreateResult struct {
-	Status        CreateStatus
-	RequestedName string
-	Name          string
-	Username      string
-	UserHost      string
-	Password      string
-	Message       string
-	CSVExported   bool
+	Status           CreateStatus
+	RequestedName    string
+	Name             string
+	Username         string
+	UserHost         string
+	UserHosts        []string // all hosts, when the user was created on several
+	Profile          string
+	Auth             string // set when not the default
+	Password         string
+	ReadOnlyUsername string
+	ReadOnlyPassword string
+	Message          string
+	CSVExported      bool
 }
 
-func openDB(cfg map[string]string, timeout time.Duration) (*sql.DB, error) {
+// mysqlConfig builds the driver config from a [mariadb] section. A socket
+// replaces hostname/port; ssl-* keys are handled by setupTLS.
+func mysqlConfig(cfg map[string]string) (*mysql.Config, error) {
 	user := cfg["username"]
 	pass := cfg["password"]
 	host := cfg["hostname"]
 	port := cfg["port"]
+	socket := cfg["socket"]
+
+	c := mysql.NewConfig()
+	c.User = user
+	c.Passwd = pass
+	c.ParseTime = true
+	c.Loc = time.Local
+	c.Params = map[string]string{"charset": "utf8mb4"}
 
-	if user == "" || host == "" || port == "" {
-		return nil, fmt.Errorf("config missing required fields (username/hostname/port)")
+	switch {
+	case user == "":
+		return nil, fmt.Errorf("config missing required field username")
+	case socket != "":
+		c.Net = "unix"
+		c.Addr = socket
+	case host == "" || port == "":
+		return nil, fmt.Errorf("config missing required fields (hostname/port or socket)")
+	default:
+		c.Net = "tcp"
+		c.Addr = net.JoinHostPort(host, port)
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=true&loc=Local",
-		user, pass, host, port)
+	if _, err := setupTLS(c, cfg, host); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
 
-	db, err := sql.Open("mysql", dsn)
+func openDB(cfg map[string]string, timeout time.Duration) (*sql.DB, error) {
+	c, err := mysqlConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", c.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +172,7 @@ func openDB(cfg map[string]string, timeout time.Duration) (*sql.DB, error) {
 
 var hostNoWildcardRe = regexp.MustCompile(`^[a-zA-Z0-9.-]+$`)
 var hostWildcardRe = regexp.MustCompile(`^[a-zA-Z0-9.%_-]+$`)
+var ipv4LikeRe = regexp.MustCompile(`^[0-9.]+$`)
 
 func validateUserHost(user, host string, allowWildcards bool) error {
 	if err := validateIdentifier(user); err != nil {
@@ -113,8 +187,25 @@ func validateUserHost(user, host string, allowWildcards bool) error {
 		return errors.New("host too long")
 	}
 
+	// Subnets are exact ranges, not patterns, so they need no opt-in.
+	if strings.Contains(host, "/") {
+		return validateSubnetHost(host, allowWildcards)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Zone() != "" {
+			return fmt.Errorf("invalid host '%s': zoned IPv6 addresses are not supported", host)
+		}
+		return nil
+	}
+	if strings.Contains(host, ":") {
+		return fmt.Errorf("invalid host '%s': not an IPv6 address", host)
+	}
+	if ipv4LikeRe.MatchString(host) {
+		return fmt.Errorf("invalid host '%s': not an IPv4 address", host)
+	}
+
 	if !allowWildcards {
-		if strings.Contains(host, "%") || strings.Contains(host, "_") {
+		if hasWildcardHost(host) {
 			return fmt.Errorf("wildcard host not allowed ('%%' or '_' found). Use -allow-wildcard-host to permit it")
 		}
 		if !hostNoWildcardRe.MatchString(host) {
@@ -130,6 +221,91 @@ func validateUserHost(user, host string, allowWildcards bool) error {
 	return nil
 }
 
+// validateSubnetHost accepts MariaDB's ip/netmask form (IPv4 only, as in
+// MariaDB) with a contiguous netmask and no host bits set.
+func validateSubnetHost(host string, allowWildcards bool) error {
+	// CIDR is translated by canonicalHost; what is left cannot be used.
+	if p, err := netip.ParsePrefix(host); err == nil {
+		switch {
+		case !p.Addr().Is4():
+			return fmt.Errorf("invalid host '%s': MariaDB has no IPv6 subnets", host)
+		case p != p.Masked():
+			return fmt.Errorf("invalid host '%s': address has host bits set (did you mean %s?)", host, p.Masked())
+		default:
+			return fmt.Errorf("invalid host '%s': write it as %s", host, canonicalHost(host))
+		}
+	}
+
+	addrPart, maskPart, _ := strings.Cut(host, "/")
+	addr, err1 := netip.ParseAddr(addrPart)
+	mask, err2 := netip.ParseAddr(maskPart)
+	if err1 != nil || err2 != nil || !addr.Is4() || !mask.Is4() {
+		return fmt.Errorf("invalid host '%s' (expected CIDR like 10.20.0.0/16 or ip/netmask)", host)
+	}
+	ones := netmaskBits(mask)
+	if ones < 0 {
+		return fmt.Errorf("invalid host '%s': netmask %s is not contiguous", host, maskPart)
+	}
+	if ones == 0 && !allowWildcards {
+		return fmt.Errorf("host '%s' matches every address. Use -allow-wildcard-host to permit it", host)
+	}
+	if p := netip.PrefixFrom(addr, ones); p != p.Masked() {
+		return fmt.Errorf("invalid host '%s': address has host bits set (did you mean %s?)",
+			host, canonicalHost(p.Masked().String()))
+	}
+	return nil
+}
+
+// netmaskBits returns the prefix length of an IPv4 netmask, or -1 if the
+// mask is not contiguous.
+func netmaskBits(mask netip.Addr) int {
+	b := mask.As4()
+	v := binary.BigEndian.Uint32(b[:])
+	ones := bits.LeadingZeros32(^v)
+	if ones < 32 && v != ^uint32(0)<<(32-ones) {
+		return -1
+	}
+	return ones
+}
+
+// canonicalHost rewrites CIDR notation (10.20.0.0/16) to the ip/netmask
+// form MariaDB matches on (10.20.0.0/255.255.0.0), a single-address
+// prefix to the address, and IP literals to their canonical spelling.
+// Anything else is returned as given, for validateUserHost to judge.
+func canonicalHost(host string) string {
+	host = strings.TrimSpace(host)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String()
+	}
+	p, err := netip.ParsePrefix(host)
+	if err != nil || p != p.Masked() {
+		return host
+	}
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	if !p.Addr().Is4() {
+		return host
+	}
+	mask := netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, ^uint32(0)<<(32-p.Bits()))))
+	return p.Addr().String() + "/" + mask.String()
+}
+
+// hasWildcardHost reports whether host is a pattern rather than one host.
+func hasWildcardHost(host string) bool {
+	return strings.ContainsAny(host, "%_")
+}
+
+// validateUserHosts validates user on each of hosts.
+func validateUserHosts(user string, hosts []string, allowWildcards bool) error {
+	for _, h := range hosts {
+		if err := validateUserHost(user, h, allowWildcards); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func quoteUserHost(user, host string) string {
 	return fmt.Sprintf("'%s'@'%s'", user, host)
 }
@@ -142,6 +318,71 @@ func escapeSQLStringLiteral(s string) string {
    Existence checks
 ================================= */
 
+// collationCharset returns the character set of collation, or "" if the
+// server does not know it.
+func collationCharset(ctx context.Context, db *sql.DB, collation string) (string, error) {
+	var cs sql.NullString
+	err := db.QueryRowContext(ctx,
+		`SELECT CHARACTER_SET_NAME
+		 FROM information_schema.COLLATIONS
+		 WHERE COLLATION_NAME = ?`, collation,
+	).Scan(&cs)
+	if errors.Is(err, sql.ErrNoRows) {
+		// MariaDB 10.10+ lists the uca1400 collations once, without a
+		// character set; full names like utf8mb4_uca1400_ai_ci are only in
+		// COLLATION_CHARACTER_SET_APPLICABILITY.
+		err = db.QueryRowContext(ctx,
+			`SELECT CHARACTER_SET_NAME
+			 FROM information_schema.COLLATION_CHARACTER_SET_APPLICABILITY
+			 WHERE FULL_COLLATION_NAME = ?`, collation,
+		).Scan(&cs)
+		var me *mysql.MySQLError
+		if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &me) && me.Number == 1054) {
+			// 1054: older servers have no FULL_COLLATION_NAME column.
+			return "", nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return cs.String, nil
+}
+
+// checkCharsetCollation verifies charset and collation against
+// information_schema.COLLATIONS and that they belong together. It returns
+// the reason they cannot be used, or "" if they can. Empty values mean
+// the server default.
+func checkCharsetCollation(ctx context.Context, db *sql.DB, charset, collation string) (string, error) {
+	if collation != "" {
+		cs, err := collationCharset(ctx, db, collation)
+		if err != nil {
+			return "", fmt.Errorf("check collation: %w", err)
+		}
+		if cs == "" {
+			return fmt.Sprintf("unknown collation '%s'", collation), nil
+		}
+		if charset != "" && cs != charset {
+			return fmt.Sprintf("collation '%s' belongs to character set '%s', not '%s'", collation, cs, charset), nil
+		}
+		return "", nil
+	}
+	if charset == "" {
+		return "", nil
+	}
+	var n int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.COLLATIONS
+		 WHERE CHARACTER_SET_NAME = ?`, charset,
+	).Scan(&n); err != nil {
+		return "", fmt.Errorf("check character set: %w", err)
+	}
+	if n == 0 {
+		return fmt.Sprintf("unknown character set '%s'", charset), nil
+	}
+	return "", nil
+}
+
 func userExists(ctx context.Context, db *sql.DB, user, host string) (bool, error) {
 	grantee := fmt.Sprintf("'%s'@'%s'", user, host)
 
@@ -162,62 +403,142 @@ func userExists(ctx context.Context, db *sql.DB, user, host string) (bool, error
 	return false, err
 }
 
-func dbOrUserExists(ctx context.Context, db *sql.DB, name, host string) (bool, bool, error) {
-
-	// Check database existence
+func databaseExists(ctx context.Context, db *sql.DB, name string) (bool, error) {
 	var tmp string
-	dbErr := db.QueryRowContext(ctx,
+	err := db.QueryRowContext(ctx,
 		"SELECT SCHEMA_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?",
 		name,
 	).Scan(&tmp)
 
-	var dbExists bool
 	switch {
-	case dbErr == nil:
-		dbExists = true
-	case errors.Is(dbErr, sql.ErrNoRows):
-		dbExists = false
+	case err == nil:
+		return true, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return false, nil
 	default:
-		return false, false, fmt.Errorf("check db exists: %w", dbErr)
+		return false, fmt.Errorf("check db exists: %w", err)
 	}
+}
 
-	// Check user existence via information_schema
-	uExists, err := userExists(ctx, db, name, host)
+// dbOrUserExists checks the database and each of users (default: the user
+// named like the database) on every host. It returns the accounts that
+// exist, quoted as 'user'@'host'.
+func dbOrUserExists(ctx context.Context, db *sql.DB, name string, hosts []string, users ...string) (bool, []string, error) {
+
+	dbExists, err := databaseExists(ctx, db, name)
 	if err != nil {
-		return false, false, fmt.Errorf("check user exists: %w", err)
+		return false, nil, err
+	}
+
+	if len(users) == 0 {
+		users = []string{name}
 	}
 
-	return dbExists, uExists, nil
+	// Check user existence via information_schema
+	var existing []string
+	for _, u := range users {
+		for _, h := range hosts {
+			uExists, err := userExists(ctx, db, u, h)
+			if err != nil {
+				return false, nil, fmt.Errorf("check user exists: %w", err)
+			}
+			if uExists {
+				existing = append(existing, quoteUserHost(u, h))
+			}
+		}
+	}
+
+	return dbExists, existing, nil
 }
 
-/* ===============================
-   Main creation logic
-================================= */
+func quoteUsersHost(users []string, host string) string {
+	return strings.Join(accountList(users, []string{host}), ", ")
+}
 
-func processDatabase(db *sql.DB, opts Options, inputName string) (*CreateResult, error) {
+// accountList quotes every user on every host, user by user.
+func accountList(users, hosts []string) []string {
+	var quoted []string
+	for _, u := range users {
+		for _, h := range hosts {
+			quoted = append(quoted, quoteUserHost(u, h))
+		}
+	}
+	return quoted
+}
 
-	if opts.UserHost == "" {
-		opts.UserHost = "localhost"
+// userHosts splits a -user-host value into its distinct hosts, in order.
+// Several hosts are given comma-separated or by repeating the flag. CIDR
+// subnets are translated by canonicalHost.
+func userHosts(v string) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, h := range strings.Split(v, ",") {
+		h = canonicalHost(h)
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		hosts = append(hosts, h)
 	}
+	if len(hosts) == 0 {
+		return []string{"localhost"}
+	}
+	return hosts
+}
+
+/* ===============================
+   Main creation logic
+================================= */
 
+// resolveName trims the input and, with -normalize, turns it into the
+// identifier used for both the database and the user.
+func resolveName(opts Options, inputName string) (string, string, error) {
 	requested := strings.TrimSpace(inputName)
 	if requested == "" {
-		return nil, errors.New("empty name")
+		return "", "", errors.New("empty name")
 	}
 
 	name := requested
 
 	if opts.Normalize {
 		if err := validateRawNameForNormalization(requested); err != nil {
-			return nil, err
+			return "", "", err
 		}
 		name = normalizeName(requested)
 		if name == "" {
-			return nil, fmt.Errorf("name '%s' normalizes to empty identifier", requested)
+			return "", "", fmt.Errorf("name '%s' normalizes to empty identifier", requested)
 		}
 	}
 
-	if err := validateUserHost(name, opts.UserHost, opts.AllowWildcardHost); err != nil {
+	if name == registrySchema {
+		return "", "", fmt.Errorf("name '%s' is reserved for the registry", name)
+	}
+
+	return requested, name, nil
+}
+
+// withCreateDefaults fills in what processDatabase assumes when unset.
+func withCreateDefaults(opts Options) Options {
+	if opts.UserHost == "" {
+		opts.UserHost = "localhost"
+	}
+	if len(opts.Privileges.Privileges) == 0 {
+		opts.Privileges = PrivilegeProfile{Name: defaultPrivilegeProfile, Privileges: []string{allPrivileges}}
+	}
+	return opts
+}
+
+func processDatabase(ctx context.Context, db *sql.DB, opts Options, inputName string) (*CreateResult, error) {
+
+	opts = withCreateDefaults(opts)
+
+	requested, name, err := resolveName(opts, inputName)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := userHosts(opts.UserHost)
+	if err := validateUserHosts(name, hosts, opts.AllowWildcardHost); err != nil {
 		return nil, err
 	}
 
@@ -226,92 +547,358 @@ func processDatabase(db *sql.DB, opts Options, inputName string) (*CreateResult,
 		RequestedName: requested,
 		Name:          name,
 		Username:      name,
-		UserHost:      opts.UserHost,
+		UserHost:      hosts[0],
+		UserHosts:     hosts,
+		Profile:       opts.Privileges.Name,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
 
-	dbExists, userExists, err := dbOrUserExists(ctx, db, name, opts.UserHost)
+	users := []string{name}
+	if opts.ReadOnlyUser {
+		roName := readOnlyUserName(name)
+		if err := validateUserHosts(roName, hosts, opts.AllowWildcardHost); err != nil {
+			return nil, fmt.Errorf("read-only user: %w", err)
+		}
+		users = append(users, roName)
+	}
+
+	// An unknown charset or collation would fail after nothing else could.
+	reason, err := checkCharsetCollation(ctx, db, opts.Charset, opts.Collation)
 	if err != nil {
 		return nil, err
 	}
+	if reason != "" {
+		return nil, errors.New(reason)
+	}
+	reason, err = checkAuthPlugins(ctx, db, opts.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return nil, errors.New(reason)
+	}
+	if !opts.Auth.isDefault() {
+		res.Auth = opts.Auth.String()
+	}
 
-	if dbExists || userExists {
+	// Every host must be free: one existing entry would leave the account
+	// with two passwords.
+	dbExists, existingAccounts, err := dbOrUserExists(ctx, db, name, hosts, users...)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbExists || len(existingAccounts) > 0 {
 		res.Status = StatusSkipped
 		switch {
-		case dbExists && userExists:
+		case dbExists && len(existingAccounts) > 0:
 			res.Message = fmt.Sprintf("Skipping '%s': database exists and user %s exists",
-				name, quoteUserHost(name, opts.UserHost))
+				name, strings.Join(existingAccounts, ", "))
 		case dbExists:
 			res.Message = fmt.Sprintf("Skipping '%s': database exists (will not create user)", name)
-		case userExists:
+		default:
 			res.Message = fmt.Sprintf("Skipping '%s': user %s exists (will not create database)",
-				name, quoteUserHost(name, opts.UserHost))
+				name, strings.Join(existingAccounts, ", "))
 		}
 		return res, nil
 	}
 
-	pw, err := generatePassword(20)
-	if err != nil {
-		return nil, err
+	// Socket-only users have no password to generate or show.
+	accounts := createAccounts(opts, name)
+	for i := range accounts {
+		if !opts.Auth.usesPassword() {
+			break
+		}
+		pw, err := opts.PasswordPolicy.generate()
+		if err != nil {
+			return nil, err
+		}
+		accounts[i].Password = pw
+	}
+	res.Password = accounts[0].Password
+	if opts.ReadOnlyUser {
+		res.ReadOnlyUsername = accounts[1].User
+		res.ReadOnlyPassword = accounts[1].Password
 	}
-	res.Password = pw
 
 	if opts.DryRun {
 		res.Status = StatusDryRun
+		var grants []string
+		for _, a := range accounts {
+			for _, h := range hosts {
+				grants = append(grants, a.grantSQL(name, h))
+			}
+		}
+		res.Message = fmt.Sprintf("Would run %s, create user %s and run: %s (profile %s, auth %s)",
+			createDatabaseSQL(name, opts.Charset, opts.Collation), strings.Join(accountList(users, hosts), ", "),
+			strings.Join(grants, "; "), opts.Privileges.Name, opts.Auth)
 		return res, nil
 	}
 
+	// From here on the create finishes or rolls back even if the run is
+	// interrupted.
+	ctx, cancelCommit, err := commitContext(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelCommit()
+
+	lock, err := lockJournal(opts.JournalPath, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	j, err := beginCreateJournal(opts, name, users, hosts)
+	if err != nil {
+		return nil, err
+	}
+	// rollback undoes the create and closes the journal entry, unless the
+	// rollback itself failed: then recover has to finish it.
+	var created []string
+	rollback := func() {
+		if err := rollbackCreate(ctx, db, name, created...); err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Rollback of '%s' incomplete (run recover): %v", name, err))
+			return
+		}
+		j.end(opts, journalRolledBack, "")
+	}
+
 	// CREATE DATABASE
-	if err := execSQL(ctx, db, "CREATE DATABASE "+quoteIdent(name)); err != nil {
+	if err := j.step(stepCreateDatabase, "", ""); err != nil {
+		return nil, err
+	}
+	if err := execSQL(ctx, db, createDatabaseSQL(name, opts.Charset, opts.Collation)); err != nil {
+		// After a timeout or lost connection the server may still have
+		// run the DDL: keep the entry open for recover unless the database
+		// is known to be absent.
+		if createOutcomeUnknown(err) && !createDatabaseAbsent(db, opts, name) {
+			return nil, fmt.Errorf("create database %s (may have been created, run recover): %w", name, err)
+		}
+		j.end(opts, journalRolledBack, "create database failed")
 		return nil, fmt.Errorf("create database %s: %w", name, err)
 	}
 
-	// CREATE USER
-	createUserSQL := "CREATE USER " + quoteUserHost(name, opts.UserHost) +
-		" IDENTIFIED BY '" + escapeSQLStringLiteral(pw) + "'"
+	// Every host entry of a user gets the same password and grant.
+	for _, a := range accounts {
+		for _, h := range hosts {
+			account := quoteUserHost(a.User, h)
 
-	if err := execSQL(ctx, db, createUserSQL); err != nil {
-		_ = execSQL(ctx, db, "DROP DATABASE "+quoteIdent(name))
-		return nil, fmt.Errorf("create user %s: %w", quoteUserHost(name, opts.UserHost), err)
-	}
+			// CREATE USER
+			if err := j.step(stepCreateUser, a.User, h); err != nil {
+				rollback()
+				return nil, err
+			}
+			if err := execSQL(ctx, db, createUserSQL(a.User, h, opts.Auth, a.Password)); err != nil {
+				rollback()
+				return nil, fmt.Errorf("create user %s: %w", account, err)
+			}
+			created = append(created, account)
 
-	// GRANT
-	grantSQL := "GRANT ALL PRIVILEGES ON " + quoteIdent(name) +
-		".* TO " + quoteUserHost(name, opts.UserHost)
+			// GRANT
+			if err := j.step(stepGrant, a.User, h); err != nil {
+				rollback()
+				return nil, err
+			}
+			if err := execSQL(ctx, db, a.grantSQL(name, h)); err != nil {
+				rollback()
+				if a.User != name {
+					return nil, fmt.Errorf("grant SELECT for %s: %w", account, err)
+				}
+				return nil, fmt.Errorf("grant %s privileges (profile %s) for %s: %w",
+					opts.Privileges.grantList(), opts.Privileges.Name, account, err)
+			}
+		}
+	}
 
-	if err := execSQL(ctx, db, grantSQL); err != nil {
-		_ = execSQL(ctx, db, "DROP USER "+quoteUserHost(name, opts.UserHost))
-		_ = execSQL(ctx, db, "DROP DATABASE "+quoteIdent(name))
-		return nil, fmt.Errorf("grant privileges for %s: %w", name, err)
+	// Unregistered accounts could never be deleted or rotated by the tool.
+	var entries []RegistryEntry
+	for _, a := range accounts {
+		profile := ""
+		if a.Role == roleOwner {
+			profile = opts.Privileges.Name
+		}
+		for _, h := range hosts {
+			entries = append(entries, newRegistryEntry(opts, name, a.User, h, a.Role, profile, a.Privileges))
+		}
 	}
+	if err := j.step(stepRegister, "", ""); err != nil {
+		rollback()
+		return nil, err
+	}
+	if err := recordAccounts(ctx, db, entries); err != nil {
+		rollback()
+		return nil, err
+	}
+	j.end(opts, journalDone, "")
 
 	res.Status = StatusCreated
 
-	if opts.ExportCSV {
-		if err := saveToCSV(opts.CSVPath, name, name, pw); err != nil {
-			msg := fmt.Sprintf("WARNING: failed to export CSV for %s: %v", name, err)
-			logError(opts.ErrorLogPath, msg)
-		} else {
-			res.CSVExported = true
+	if opts.ExportCSV && opts.Auth.usesPassword() {
+		res.CSVExported = true
+		for _, a := range accounts {
+			if err := saveToCSV(opts.CSVPath, name, a.User, a.Password); err != nil {
+				msg := fmt.Sprintf("WARNING: failed to export CSV for %s: %v", a.User, err)
+				logError(opts.ErrorLogPath, msg)
+				res.CSVExported = false
+			}
 		}
 	}
 
 	return res, nil
 }
 
+// createOutcomeUnknown reports whether a failed statement may still have
+// run: it timed out or the connection was lost. An error the server
+// returned (e.g. 1007, database exists) means it did not.
+func createOutcomeUnknown(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return false
+	}
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.As(err, &ne)
+}
+
+// createDatabaseAbsent reports whether database name is known not to
+// exist after a failed CREATE DATABASE. The create's own context may be
+// what failed, so it checks with a fresh one.
+func createDatabaseAbsent(db *sql.DB, opts Options, name string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	exists, err := databaseExists(ctx, db, name)
+	return err == nil && !exists
+}
+
+// createAccount is one user processDatabase creates, on every host.
+type createAccount struct {
+	User       string
+	Role       string
+	Privileges string
+	Password   string
+}
+
+func (a createAccount) grantSQL(name, host string) string {
+	return grantSQL(a.Privileges, name, a.User, host)
+}
+
+// createAccounts lists the users created for database name, without
+// passwords: the primary user with the profile's privileges and, with
+// -readonly-user, the SELECT-only companion.
+func createAccounts(opts Options, name string) []createAccount {
+	accounts := []createAccount{{
+		User:       name,
+		Role:       roleOwner,
+		Privileges: opts.Privileges.grantList(),
+	}}
+	if opts.ReadOnlyUser {
+		roName := readOnlyUserName(name)
+		accounts = append(accounts, createAccount{
+			User:       roName,
+			Role:       roleReadOnly,
+			Privileges: "SELECT",
+		})
+	}
+	return accounts
+}
+
+func grantSQL(privs, name, user, host string) string {
+	return "GRANT " + privs + " ON " + quoteIdent(name) + ".* TO " + quoteUserHost(user, host)
+}
+
+func revokeSQL(privs, name, user, host string) string {
+	return "REVOKE " + privs + " ON " + quoteIdent(name) + ".* FROM " + quoteUserHost(user, host)
+}
+
+// createDatabaseSQL renders CREATE DATABASE; charset and collation must
+// already be validated (see validateCharset).
+func createDatabaseSQL(name, charset, collation string) string {
+	q := "CREATE DATABASE " + quoteIdent(name)
+	if charset != "" {
+		q += " CHARACTER SET " + charset
+	}
+	if collation != "" {
+		q += " COLLATE " + collation
+	}
+	return q
+}
+
+func createUserSQL(user, host string, auth AuthSpec, password string) string {
+	return "CREATE USER " + quoteUserHost(user, host) + auth.identifiedSQL(password)
+}
+
+// readOnlyUserName is the companion account created with -readonly-user.
+func readOnlyUserName(name string) string {
+	return name + "_ro"
+}
+
+// rollbackCreate removes what processDatabase created so far; accounts
+// are quoted 'user'@'host'. Dropping a user also removes whatever part of
+// its grant MariaDB managed to apply. Every step is attempted; the first
+// error is returned.
+func rollbackCreate(ctx context.Context, db *sql.DB, name string, accounts ...string) error {
+	var first error
+	for _, a := range accounts {
+		if err := execSQL(ctx, db, "DROP USER "+a); err != nil && first == nil {
+			first = err
+		}
+	}
+	if err := execSQL(ctx, db, "DROP DATABASE "+quoteIdent(name)); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
+
 /* ===============================
    Batch mode
 ================================= */
 
-func processFile(db *sql.DB, opts Options, filename string) error {
+// itemFunc is the per-name operation run by single mode and batch mode.
+type itemFunc func(ctx context.Context, db *sql.DB, opts Options, name string) (*CreateResult, error)
+
+// batchEntry is one name from a batch file with its line number. Name,
+// when set, replaces Input as the name to process (see -disambiguate).
+// Charset and Collation are per-line overrides for create.
+type batchEntry struct {
+	Line      int
+	Input     string
+	Name      string
+	Charset   string
+	Collation string
+}
+
+// options applies the line's overrides. A line that sets either value
+// replaces both, so a global collation never meets a line's charset.
+func (e batchEntry) options(opts Options) Options {
+	if e.Charset != "" || e.Collation != "" {
+		opts.Charset, opts.Collation = e.Charset, e.Collation
+	}
+	return opts
+}
+
+func (e batchEntry) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Input
+}
+
+// readBatchFile returns the names in a batch file, skipping blank lines
+// and comments (# or ;, also at end of line). A name may be followed by
+// charset=<cs> and collation=<name>.
+func readBatchFile(filename string) ([]batchEntry, error) {
 	f, err := os.Open(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
+	var entries []batchEntry
 	sc := bufio.NewScanner(f)
 	lineNo := 0
 
@@ -330,32 +917,78 @@ func processFile(db *sql.DB, opts Options, filename string) error {
 			}
 		}
 
-		res, err := processDatabase(db, opts, raw)
+		e, err := parseBatchLine(lineNo, raw)
 		if err != nil {
-			msg := fmt.Sprintf("Line %d (%s): %v", lineNo, raw, err)
-			fmt.Println("❌", msg)
-			logError(opts.ErrorLogPath, msg)
-			continue
+			return nil, err
 		}
+		entries = append(entries, e)
+	}
 
-		if opts.Normalize && res.RequestedName != "" && res.RequestedName != res.Name {
-			fmt.Printf("   Requested: %s -> Normalized: %s\n",
-				res.RequestedName, res.Name)
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseBatchLine(lineNo int, raw string) (batchEntry, error) {
+	fields := strings.Fields(raw)
+	e := batchEntry{Line: lineNo, Input: fields[0]}
+	for _, f := range fields[1:] {
+		key, val, _ := strings.Cut(f, "=")
+		val = strings.ToLower(val)
+		var err error
+		switch key {
+		case "charset":
+			e.Charset, err = val, validateCharset(val)
+		case "collation":
+			e.Collation, err = val, validateCollation(val)
+		default:
+			err = fmt.Errorf("unexpected '%s' (expected charset=<cs> or collation=<name>)", f)
+		}
+		if err != nil {
+			return e, fmt.Errorf("line %d: %w", lineNo, err)
 		}
+	}
+	return e, nil
+}
 
-		switch res.Status {
-		case StatusSkipped:
-			fmt.Printf("⚠️  %s\n", res.Message)
-		case StatusDryRun:
-			fmt.Printf("✅ DRY-RUN OK: %s\n", res.Name)
-		case StatusCreated:
-			fmt.Printf("✅ Success: %s created.\n", res.Name)
-			fmt.Printf("   Username: %s\n   Host:     %s\n   Password: %s\n",
-				res.Username, res.UserHost, res.Password)
+func processFile(ctx context.Context, db *sql.DB, opts Options, filename string, fn itemFunc, rep reporter) error {
+	entries, err := readBatchFile(filename)
+	if err != nil {
+		return err
+	}
+
+	// Nothing touches the server until the whole file is known to be
+	// free of names that would end up as the same identifier.
+	entries, err = checkBatchCollisions(opts, entries)
+	if err != nil {
+		return err
+	}
+	if err := checkBatchCharsets(ctx, db, opts, entries); err != nil {
+		return err
+	}
+
+	cp, done, err := openCheckpoint(opts, filename)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if cp != nil {
+		rep = &checkpointReporter{reporter: rep, cp: cp, opts: opts}
+	}
+
+	// With -resume, lines completed by an earlier run are reported first
+	// and not run again.
+	var remaining []batchEntry
+	for _, e := range entries {
+		if l, ok := done[e.Line]; ok && l.Input == e.Input {
+			rep.previous(e.Line, e.Input, l.Status)
+			continue
 		}
+		remaining = append(remaining, e)
 	}
 
-	return sc.Err()
+	runBatch(ctx, db, opts, remaining, fn, rep)
+	return rep.finish()
 }
 
 func execSQL(ctx context.Context, db *sql.DB, query string) error {
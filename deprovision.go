@@ -0,0 +1,249 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* ===============================
+   Managed-account checks
+================================= */
+
+// checkToolManaged reports whether user@host on database name is in the
+// registry and still carries only the grants the tool hands out. A
+// non-empty reason explains why not.
+func checkToolManaged(ctx context.Context, db *sql.DB, user, host, name string) (string, error) {
+	registered, err := isRegistered(ctx, db, name, user, host)
+	if err != nil {
+		return "", err
+	}
+	if !registered {
+		return fmt.Sprintf("user %s on '%s' is not in the registry (see adopt)",
+			quoteUserHost(user, host), name), nil
+	}
+	return checkGrantsManaged(ctx, db, user, host, name)
+}
+
+// checkGrantsManaged reports whether user@host carries exactly the grants
+// processDatabase hands out: nothing global beyond USAGE and privileges on
+// database name only.
+func checkGrantsManaged(ctx context.Context, db *sql.DB, user, host, name string) (string, error) {
+	grantee := quoteUserHost(user, host)
+
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.USER_PRIVILEGES
+		 WHERE GRANTEE = ? AND PRIVILEGE_TYPE <> 'USAGE'`, grantee,
+	).Scan(&n)
+	if err != nil {
+		return "", fmt.Errorf("check global privileges: %w", err)
+	}
+	if n > 0 {
+		return fmt.Sprintf("user %s has global privileges", grantee), nil
+	}
+
+	err = db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.SCHEMA_PRIVILEGES
+		 WHERE GRANTEE = ? AND TABLE_SCHEMA <> ?`, grantee, name,
+	).Scan(&n)
+	if err != nil {
+		return "", fmt.Errorf("check schema privileges: %w", err)
+	}
+	if n > 0 {
+		return fmt.Sprintf("user %s has privileges on other databases", grantee), nil
+	}
+
+	err = db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.SCHEMA_PRIVILEGES
+		 WHERE GRANTEE = ? AND TABLE_SCHEMA = ?`, grantee, name,
+	).Scan(&n)
+	if err != nil {
+		return "", fmt.Errorf("check schema privileges: %w", err)
+	}
+	if n == 0 {
+		return fmt.Sprintf("user %s has no privileges on database '%s'", grantee, name), nil
+	}
+
+	return "", nil
+}
+
+/* ===============================
+   Deprovisioning
+================================= */
+
+// deprovisionDatabase is the inverse of processDatabase. It is just as
+// fail-closed: both the database and the user must exist and look
+// tool-managed, and the operator must confirm, before anything is dropped.
+func deprovisionDatabase(ctx context.Context, db *sql.DB, opts Options, inputName string) (*CreateResult, error) {
+
+	if opts.UserHost == "" {
+		opts.UserHost = "localhost"
+	}
+
+	requested, name, err := resolveName(opts, inputName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateUserHost(name, opts.UserHost, opts.AllowWildcardHost); err != nil {
+		return nil, err
+	}
+
+	res := &CreateResult{
+		Status:        StatusUnknown,
+		RequestedName: requested,
+		Name:          name,
+		Username:      name,
+		UserHost:      opts.UserHost,
+	}
+
+	runCtx := ctx
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	dbExists, existingUsers, err := dbOrUserExists(ctx, db, name, []string{opts.UserHost})
+	if err != nil {
+		return nil, err
+	}
+	uExists := len(existingUsers) > 0
+
+	if !dbExists || !uExists {
+		res.Status = StatusSkipped
+		switch {
+		case !dbExists && !uExists:
+			res.Message = fmt.Sprintf("Skipping '%s': neither database nor user %s exists",
+				name, quoteUserHost(name, opts.UserHost))
+		case !dbExists:
+			res.Message = fmt.Sprintf("Skipping '%s': database missing (will not drop user)", name)
+		case !uExists:
+			res.Message = fmt.Sprintf("Skipping '%s': user %s missing (will not drop database)",
+				name, quoteUserHost(name, opts.UserHost))
+		}
+		return res, nil
+	}
+
+	reason, err := checkToolManaged(ctx, db, name, opts.UserHost, name)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		res.Status = StatusSkipped
+		res.Message = fmt.Sprintf("Skipping '%s': %s (not tool-managed)", name, reason)
+		return res, nil
+	}
+
+	// Every other registered account on the database (the -readonly-user
+	// companion, extra users from sync) goes together with it.
+	regs, err := registryEntries(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	drop := []RegistryEntry{{Database: name, User: name, Host: opts.UserHost}}
+	var also []string
+	for _, e := range regs {
+		if e.User == name && e.Host == opts.UserHost {
+			continue
+		}
+		exists, err := userExists(ctx, db, e.User, e.Host)
+		if err != nil {
+			return nil, fmt.Errorf("check user exists: %w", err)
+		}
+		if !exists {
+			continue
+		}
+		reason, err := checkToolManaged(ctx, db, e.User, e.Host, name)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			res.Status = StatusSkipped
+			res.Message = fmt.Sprintf("Skipping '%s': %s (not tool-managed)", name, reason)
+			return res, nil
+		}
+		drop = append(drop, e)
+		if e.Role == roleReadOnly && e.Host == opts.UserHost {
+			res.ReadOnlyUsername = e.User
+		} else {
+			also = append(also, quoteUserHost(e.User, e.Host))
+		}
+	}
+	if len(also) > 0 {
+		res.Message = "Also dropped: " + strings.Join(also, ", ")
+	}
+
+	accounts := make([]string, len(drop))
+	for i, e := range drop {
+		accounts[i] = quoteUserHost(e.User, e.Host)
+	}
+
+	if opts.DryRun {
+		res.Status = StatusDryRun
+		res.Message = fmt.Sprintf("Would drop user %s and database '%s'",
+			strings.Join(accounts, ", "), name)
+		return res, nil
+	}
+
+	// The prompt may take longer than the operation timeout.
+	if !opts.Yes && !confirmDeletion(name, accounts) {
+		res.Status = StatusSkipped
+		res.Message = fmt.Sprintf("Skipping '%s': not confirmed", name)
+		return res, nil
+	}
+
+	ctx, cancel, err = commitContext(runCtx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// DROP USER first so the accounts lose access before their data goes away.
+	for i, e := range drop {
+		if err := execSQL(ctx, db, "DROP USER "+accounts[i]); err != nil {
+			return nil, fmt.Errorf("drop user %s: %w", accounts[i], err)
+		}
+		if err := unregisterAccount(ctx, db, name, e.User, e.Host); err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Registry cleanup failed (%s): %v", accounts[i], err))
+		}
+	}
+
+	if err := execSQL(ctx, db, "DROP DATABASE "+quoteIdent(name)); err != nil {
+		return nil, fmt.Errorf("drop database %s (users already dropped): %w", name, err)
+	}
+
+	res.Status = StatusDeleted
+	return res, nil
+}
+
+// confirmDeletion asks the operator to type the database name back. It
+// names every account that goes with the database.
+func confirmDeletion(name string, accounts []string) bool {
+	users := "user"
+	if len(accounts) > 1 {
+		users = "users"
+	}
+	// Prompt on stderr so -output json stays parseable.
+	fmt.Fprintf(os.Stderr, "Drop database '%s' and %s %s? Type the name to confirm: ",
+		name, users, strings.Join(accounts, ", "))
+	var answer string
+	fmt.Scanln(&answer)
+	return strings.TrimSpace(answer) == name
+}
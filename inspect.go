@@ -0,0 +1,482 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/* ===============================
+   list / inspect
+================================= */
+
+// systemSchemas are the server's own databases; list never shows them.
+var systemSchemas = map[string]bool{
+	"information_schema": true,
+	"mysql":              true,
+	"performance_schema": true,
+	"sys":                true,
+	registrySchema:       true,
+}
+
+// DatabaseInfo is one database as seen by list and inspect.
+type DatabaseInfo struct {
+	Database  string   `json:"database"`
+	Exists    bool     `json:"exists"`
+	Charset   string   `json:"charset"`
+	Collation string   `json:"collation"`
+	Tables    int      `json:"tables"`
+	SizeBytes int64    `json:"size_bytes"`
+	Users     []string `json:"users"`
+	Managed   bool     `json:"managed"`
+	Owner     string   `json:"owner,omitempty"`
+	Ticket    string   `json:"ticket,omitempty"`
+}
+
+type AccountInfo struct {
+	User       string   `json:"user"`
+	Host       string   `json:"host"`
+	Exists     bool     `json:"exists"`
+	Registered bool     `json:"registered"`
+	Role       string   `json:"role,omitempty"`
+	Grants     []string `json:"grants"`
+}
+
+type InspectReport struct {
+	DatabaseInfo
+	Accounts []AccountInfo   `json:"accounts"`
+	Registry []RegistryEntry `json:"registry"`
+}
+
+// parseGrantee splits information_schema's 'user'@'host' form.
+func parseGrantee(g string) (user, host string, ok bool) {
+	i := strings.LastIndex(g, "'@'")
+	if i < 1 || !strings.HasPrefix(g, "'") || !strings.HasSuffix(g, "'") || len(g) < i+4 {
+		return "", "", false
+	}
+	return g[1:i], g[i+3 : len(g)-1], true
+}
+
+// accountLiteral quotes a user/host pair read from the server, which may
+// contain characters the tool itself never creates.
+func accountLiteral(user, host string) string {
+	esc := func(s string) string {
+		return escapeSQLStringLiteral(strings.ReplaceAll(s, `\`, `\\`))
+	}
+	return "'" + esc(user) + "'@'" + esc(host) + "'"
+}
+
+func showGrants(ctx context.Context, db *sql.DB, user, host string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS FOR "+accountLiteral(user, host))
+	if err != nil {
+		return nil, fmt.Errorf("show grants for %s: %w", quoteUserHost(user, host), err)
+	}
+	defer rows.Close()
+
+	grants := []string{}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, err
+		}
+		grants = append(grants, redactGrant(g))
+	}
+	return grants, rows.Err()
+}
+
+// redactGrant hides password hashes that older servers include in
+// SHOW GRANTS output.
+func redactGrant(g string) string {
+	const marker = " IDENTIFIED BY PASSWORD '"
+	i := strings.Index(g, marker)
+	if i < 0 {
+		return g
+	}
+	rest := g[i+len(marker):]
+	j := strings.Index(rest, "'")
+	if j < 0 {
+		return g[:i] + marker + redactedPassword + "'"
+	}
+	return g[:i] + marker + redactedPassword + rest[j:]
+}
+
+type schemaStats struct {
+	tables int
+	size   int64
+}
+
+// tableStats sums the tables and data+index size per schema; name limits
+// it to one schema.
+func tableStats(ctx context.Context, db *sql.DB, name string) (map[string]schemaStats, error) {
+	q := `SELECT TABLE_SCHEMA, COUNT(*), COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0)
+		FROM information_schema.TABLES`
+	var args []any
+	if name != "" {
+		q += " WHERE TABLE_SCHEMA = ?"
+		args = append(args, name)
+	}
+	q += " GROUP BY TABLE_SCHEMA"
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]schemaStats)
+	for rows.Next() {
+		var schema string
+		var s schemaStats
+		if err := rows.Scan(&schema, &s.tables, &s.size); err != nil {
+			return nil, err
+		}
+		stats[schema] = s
+	}
+	return stats, rows.Err()
+}
+
+// schemaGrantees maps each schema to the accounts with privileges on it.
+func schemaGrantees(ctx context.Context, db *sql.DB, name string) (map[string][]string, error) {
+	q := "SELECT DISTINCT TABLE_SCHEMA, GRANTEE FROM information_schema.SCHEMA_PRIVILEGES"
+	var args []any
+	if name != "" {
+		q += " WHERE TABLE_SCHEMA = ?"
+		args = append(args, name)
+	}
+	q += " ORDER BY TABLE_SCHEMA, GRANTEE"
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read schema privileges: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string][]string)
+	for rows.Next() {
+		var schema, grantee string
+		if err := rows.Scan(&schema, &grantee); err != nil {
+			return nil, err
+		}
+		out[schema] = append(out[schema], grantee)
+	}
+	return out, rows.Err()
+}
+
+// applyRegistry marks info as managed from its registry rows.
+func applyRegistry(info *DatabaseInfo, regs []RegistryEntry) {
+	for _, e := range regs {
+		if e.Role == roleOwner {
+			info.Managed = true
+			info.Owner = e.Owner
+			info.Ticket = e.Ticket
+		}
+	}
+}
+
+// listDatabases returns every non-system database plus registered ones
+// that no longer exist.
+func listDatabases(ctx context.Context, db *sql.DB) ([]DatabaseInfo, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT SCHEMA_NAME, DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME
+		 FROM information_schema.SCHEMATA`)
+	if err != nil {
+		return nil, fmt.Errorf("list databases: %w", err)
+	}
+	byName := make(map[string]*DatabaseInfo)
+	for rows.Next() {
+		info := &DatabaseInfo{Exists: true, Users: []string{}}
+		if err := rows.Scan(&info.Database, &info.Charset, &info.Collation); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !systemSchemas[info.Database] {
+			byName[info.Database] = info
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats, err := tableStats(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+	grantees, err := schemaGrantees(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+	regs, err := registryEntries(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for name, info := range byName {
+		info.Tables = stats[name].tables
+		info.SizeBytes = stats[name].size
+		if g := grantees[name]; g != nil {
+			info.Users = g
+		}
+	}
+
+	regsByDB := make(map[string][]RegistryEntry)
+	for _, e := range regs {
+		regsByDB[e.Database] = append(regsByDB[e.Database], e)
+	}
+	for name, entries := range regsByDB {
+		info, ok := byName[name]
+		if !ok {
+			info = &DatabaseInfo{Database: name, Users: []string{}}
+			byName[name] = info
+		}
+		applyRegistry(info, entries)
+	}
+
+	out := make([]DatabaseInfo, 0, len(byName))
+	for _, info := range byName {
+		out = append(out, *info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Database < out[j].Database })
+	return out, nil
+}
+
+// inspectDatabase collects everything about one database: the schema, the
+// registry rows, and all accounts related to it on any host, i.e. users
+// named like it (or its _ro companion), users with privileges on it and
+// registered users.
+func inspectDatabase(ctx context.Context, db *sql.DB, name string) (*InspectReport, error) {
+	rep := &InspectReport{
+		DatabaseInfo: DatabaseInfo{Database: name, Users: []string{}},
+		Accounts:     []AccountInfo{},
+		Registry:     []RegistryEntry{},
+	}
+
+	err := db.QueryRowContext(ctx,
+		`SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME
+		 FROM information_schema.SCHEMATA
+		 WHERE SCHEMA_NAME = ?`, name,
+	).Scan(&rep.Charset, &rep.Collation)
+	switch {
+	case err == nil:
+		rep.Exists = true
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("check db exists: %w", err)
+	}
+
+	stats, err := tableStats(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	rep.Tables, rep.SizeBytes = stats[name].tables, stats[name].size
+
+	regs, err := registryEntries(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	if regs != nil {
+		rep.Registry = regs
+	}
+	applyRegistry(&rep.DatabaseInfo, regs)
+
+	// Collect candidate accounts, keyed by grantee.
+	accounts := make(map[string]*AccountInfo)
+	add := func(user, host string) *AccountInfo {
+		key := quoteUserHost(user, host)
+		if a, ok := accounts[key]; ok {
+			return a
+		}
+		a := &AccountInfo{User: user, Host: host, Grants: []string{}}
+		accounts[key] = a
+		return a
+	}
+
+	grantees, err := schemaGrantees(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range grantees[name] {
+		if u, h, ok := parseGrantee(g); ok {
+			add(u, h)
+		}
+		rep.Users = append(rep.Users, g)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT GRANTEE FROM information_schema.USER_PRIVILEGES")
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if u, h, ok := parseGrantee(g); ok && (u == name || u == readOnlyUserName(name)) {
+			add(u, h)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range regs {
+		a := add(e.User, e.Host)
+		a.Registered = true
+		a.Role = e.Role
+	}
+
+	keys := make([]string, 0, len(accounts))
+	for k := range accounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		a := accounts[k]
+		exists, err := userExists(ctx, db, a.User, a.Host)
+		if err != nil {
+			return nil, err
+		}
+		a.Exists = exists
+		if exists {
+			if a.Grants, err = showGrants(ctx, db, a.User, a.Host); err != nil {
+				return nil, err
+			}
+		}
+		rep.Accounts = append(rep.Accounts, *a)
+	}
+
+	if !rep.Exists && len(rep.Accounts) == 0 {
+		return nil, fmt.Errorf("no database, user or registry entry found for '%s'", name)
+	}
+	return rep, nil
+}
+
+/* ===============================
+   Output
+================================= */
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// humanBytes formats n with binary units, e.g. 1.5 MiB.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func printDatabaseList(w io.Writer, opts Options, dbs []DatabaseInfo) error {
+	if opts.Output != outputText {
+		if dbs == nil {
+			dbs = []DatabaseInfo{}
+		}
+		return writeJSON(w, dbs)
+	}
+	if len(dbs) == 0 {
+		fmt.Fprintln(w, "No databases.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tCHARSET\tCOLLATION\tTABLES\tSIZE\tUSERS\tMANAGED\tOWNER\tTICKET")
+	for _, d := range dbs {
+		managed := "no"
+		switch {
+		case !d.Exists:
+			managed = "MISSING"
+		case d.Managed:
+			managed = "yes"
+		}
+		size := humanBytes(d.SizeBytes)
+		if !d.Exists {
+			size = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			d.Database, dashIfEmpty(d.Charset), dashIfEmpty(d.Collation), d.Tables, size,
+			len(d.Users), managed, dashIfEmpty(d.Owner), dashIfEmpty(d.Ticket))
+	}
+	return tw.Flush()
+}
+
+func printInspect(w io.Writer, opts Options, r *InspectReport) error {
+	if opts.Output != outputText {
+		return writeJSON(w, r)
+	}
+
+	fmt.Fprintf(w, "Database:  %s\n", r.Database)
+	if !r.Exists {
+		fmt.Fprintln(w, "Status:    MISSING")
+	} else {
+		fmt.Fprintf(w, "Charset:   %s (%s)\n", r.Charset, r.Collation)
+		fmt.Fprintf(w, "Size:      %s in %d tables\n", humanBytes(r.SizeBytes), r.Tables)
+	}
+
+	if len(r.Registry) == 0 {
+		fmt.Fprintln(w, "Registry:  not registered")
+	}
+	for _, e := range r.Registry {
+		fmt.Fprintf(w, "Registry:  %s %s, profile %s, created %s by %s (mariadb-tool %s)\n",
+			e.Role, quoteUserHost(e.User, e.Host), dashIfEmpty(e.Profile),
+			e.CreatedAt.Format(time.RFC3339), dashIfEmpty(e.CreatedBy), e.ToolVersion)
+		if e.Owner != "" || e.Ticket != "" {
+			fmt.Fprintf(w, "           owner %s, ticket %s\n", dashIfEmpty(e.Owner), dashIfEmpty(e.Ticket))
+		}
+	}
+
+	if len(r.Accounts) == 0 {
+		fmt.Fprintln(w, "\nNo related accounts.")
+		return nil
+	}
+	fmt.Fprintln(w, "\nAccounts:")
+	for _, a := range r.Accounts {
+		var flags []string
+		if a.Role != "" {
+			flags = append(flags, a.Role)
+		}
+		if a.Registered {
+			flags = append(flags, "registered")
+		} else {
+			flags = append(flags, "not registered")
+		}
+		if !a.Exists {
+			flags = append(flags, "MISSING")
+		}
+		fmt.Fprintf(w, "  %s (%s)\n", quoteUserHost(a.User, a.Host), strings.Join(flags, ", "))
+		for _, g := range a.Grants {
+			fmt.Fprintf(w, "    %s\n", g)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseGrantee(t *testing.T) {
+	u, h, ok := parseGrantee("'shop'@'10.0.%'")
+	if !ok || u != "shop" || h != "10.0.%" {
+		t.Fatalf("got %q %q %v", u, h, ok)
+	}
+	for _, bad := range []string{"", "shop@localhost", "'shop'", "'@'"} {
+		if _, _, ok := parseGrantee(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestAccountLiteral(t *testing.T) {
+	if got := accountLiteral(`o'neil\`, "localhost"); got != `'o''neil\\'@'localhost'` {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestRedactGrant(t *testing.T) {
+	g := "GRANT USAGE ON *.* TO `shop`@`localhost` IDENTIFIED BY PASSWORD '*ABCDEF0123'"
+	got := redactGrant(g)
+	if strings.Contains(got, "ABCDEF") || !strings.HasSuffix(got, "'"+redactedPassword+"'") {
+		t.Fatalf("hash not redacted: %s", got)
+	}
+	plain := "GRANT SELECT ON `shop`.* TO `shop_ro`@`localhost`"
+	if redactGrant(plain) != plain {
+		t.Fatal("grant without password changed")
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	cases := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"}
+	for n, want := range cases {
+		if got := humanBytes(n); got != want {
+			t.Fatalf("humanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestPrintDatabaseList(t *testing.T) {
+	dbs := []DatabaseInfo{
+		{Database: "blog", Exists: true, Charset: "latin1", Collation: "latin1_swedish_ci", Users: []string{}},
+		{Database: "shop", Exists: true, Charset: "utf8mb4", Collation: "utf8mb4_general_ci",
+			Tables: 3, SizeBytes: 2048, Users: []string{"'shop'@'localhost'"}, Managed: true, Ticket: "OPS-1"},
+		{Database: "gone", Users: []string{}, Managed: true},
+	}
+
+	var buf bytes.Buffer
+	if err := printDatabaseList(&buf, Options{Output: outputText}, dbs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if f := strings.Fields(lines[2]); f[0] != "shop" || f[4] != "2.0" || f[7] != "yes" || f[9] != "OPS-1" {
+		t.Fatalf("unexpected row: %q", f)
+	}
+	if f := strings.Fields(lines[1]); f[len(f)-3] != "no" {
+		t.Fatalf("blog should be unmanaged: %q", f)
+	}
+	if !strings.Contains(lines[3], "MISSING") {
+		t.Fatalf("registered but missing database not flagged: %s", lines[3])
+	}
+}
@@ -0,0 +1,100 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/* ===============================
+   Interrupts
+================================= */
+
+// interruptContext returns the root context for a run. The first SIGINT
+// or SIGTERM cancels it: nothing new starts, but work already changing
+// the server runs to the end. The default handlers are then restored, so
+// a second signal kills the process (the journal covers that case).
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			signal.Stop(sigs)
+			fmt.Fprintf(os.Stderr, "\n⚠️  %s: finishing in-flight work, starting nothing new (repeat to abort)\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+}
+
+// commitContext is used from the first statement that changes the
+// server: an interrupt must not cut a create or drop in half, so only the
+// timeout applies from here on. If the run was already interrupted the
+// item does not start.
+func commitContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	if err := ctx.Err(); errors.Is(err, context.Canceled) {
+		return nil, nil, err
+	}
+	c, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	return c, cancel, nil
+}
+
+// interrupted reports whether err means the item never ran because the
+// run was interrupted.
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
+
+// lineRanges renders sorted line numbers compactly: 1-3, 7, 9-10.
+func lineRanges(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(lines[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printInterruptSummary goes to stderr so JSON on stdout stays valid.
+func printInterruptSummary(done, notRun []int) {
+	fmt.Fprintf(os.Stderr, "⚠️  Interrupted: %d of %d lines processed\n", len(done), len(done)+len(notRun))
+	if len(done) > 0 {
+		fmt.Fprintf(os.Stderr, "   Completed: lines %s\n", lineRanges(done))
+	}
+	fmt.Fprintf(os.Stderr, "   Not run:   lines %s\n", lineRanges(notRun))
+}
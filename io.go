@@ -18,13 +18,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// Batch workers share these files; appends must not interleave.
+var (
+	logMu sync.Mutex
+	csvMu sync.Mutex
+)
+
 func logError(path, msg string) {
 	if path == "" {
 		return
 	}
+	logMu.Lock()
+	defer logMu.Unlock()
+
 	_ = os.MkdirAll(filepath.Dir(path), 0700)
 
 	// 0600: log may contain sensitive operational info
@@ -42,6 +52,8 @@ func saveToCSV(path, dbName, userName, password string) error {
 	if path == "" {
 		return fmt.Errorf("csv path is empty")
 	}
+	csvMu.Lock()
+	defer csvMu.Unlock()
 
 	_ = os.MkdirAll(filepath.Dir(path), 0700)
 
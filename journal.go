@@ -0,0 +1,483 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ===============================
+   Journal
+================================= */
+
+// The journal records each create step before it runs, so a create cut
+// short by SIGKILL or a lost connection can be finished or undone later
+// by recover. It never contains passwords.
+const (
+	journalBegin      = "begin"
+	journalStep       = "step"
+	journalDone       = "done"
+	journalRolledBack = "rolled_back"
+)
+
+const (
+	stepCreateDatabase = "create_database"
+	stepCreateUser     = "create_user"
+	stepGrant          = "grant"
+	stepRegister       = "register"
+)
+
+type JournalRecord struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Server   string    `json:"server,omitempty"`
+	Database string    `json:"database,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Hosts    []string  `json:"hosts,omitempty"`
+	Users    []string  `json:"users,omitempty"`
+	Step     string    `json:"step,omitempty"`
+	User     string    `json:"user,omitempty"`
+	Note     string    `json:"note,omitempty"`
+}
+
+var appendMu sync.Mutex
+
+// appendJSONLine appends v as one JSON line and syncs it to disk before
+// returning, so the record survives a crash right after.
+func appendJSONLine(path string, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func appendJournal(path string, r JournalRecord) error {
+	if path == "" {
+		return nil
+	}
+	r.Time = time.Now().Truncate(time.Second)
+	if err := appendJSONLine(path, r); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+	return nil
+}
+
+func newJournalID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// createJournal tracks one processDatabase run.
+type createJournal struct {
+	path string
+	id   string
+}
+
+// beginCreateJournal records the operation; Host keeps the first host so
+// entries stay readable by tools that only know one.
+func beginCreateJournal(opts Options, name string, users, hosts []string) (*createJournal, error) {
+	id, err := newJournalID()
+	if err != nil {
+		return nil, err
+	}
+	j := &createJournal{path: opts.JournalPath, id: id}
+	return j, appendJournal(j.path, JournalRecord{
+		ID:       id,
+		Event:    journalBegin,
+		Server:   serverSection(opts.Server),
+		Database: name,
+		Host:     hosts[0],
+		Hosts:    hosts,
+		Users:    users,
+	})
+}
+
+func (j *createJournal) step(step, user, host string) error {
+	return appendJournal(j.path, JournalRecord{ID: j.id, Event: journalStep, Step: step, User: user, Host: host})
+}
+
+// end closes the entry. A failed write only leaves an entry that recover
+// will find already resolved, so it is logged rather than returned.
+func (j *createJournal) end(opts Options, event, note string) {
+	if err := appendJournal(j.path, JournalRecord{ID: j.id, Event: event, Note: note}); err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Journal %s: %v", j.id, err))
+	}
+}
+
+var errJournalBusy = errors.New("journal is in use by a running create; retry when it has finished")
+
+// journalLock is a flock on the journal file. Creates hold it shared
+// from their first journal record to their last, recover holds it
+// exclusively, so recover never rolls back a create that is still
+// running in another process (or another -parallel worker).
+type journalLock struct {
+	f *os.File
+}
+
+// lockJournal returns a nil lock when the journal is disabled. Shared
+// locks wait for a running recover; exclusive ones fail at once with
+// errJournalBusy.
+func lockJournal(path string, exclusive bool) (*journalLock, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f, exclusive, !exclusive); err != nil {
+		f.Close()
+		if errors.Is(err, errJournalBusy) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("lock journal: %w", err)
+	}
+	return &journalLock{f: f}, nil
+}
+
+// unlock releases the lock; closing the file drops the flock.
+func (l *journalLock) unlock() {
+	if l != nil {
+		l.f.Close()
+	}
+}
+
+// JournalOp is one create operation folded from its journal records.
+type JournalOp struct {
+	ID       string
+	Started  time.Time
+	Server   string
+	Database string
+	Host     string
+	Hosts    []string
+	Users    []string
+	Steps    []JournalRecord
+	Closed   bool
+}
+
+// hasStep matches any user or host when user or host is empty. Steps
+// written before hosts were journalled have no host and match any.
+func (op *JournalOp) hasStep(step, user, host string) bool {
+	for _, s := range op.Steps {
+		if s.Step == step && (user == "" || s.User == user) &&
+			(host == "" || s.Host == "" || s.Host == host) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJournal folds records into operations, in the order they began.
+// A torn last line (crash mid-write) is ignored.
+func parseJournal(r io.Reader) ([]*JournalOp, error) {
+	var ops []*JournalOp
+	byID := make(map[string]*JournalOp)
+
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	var pendingErr error
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if pendingErr != nil {
+			return nil, pendingErr
+		}
+		var rec JournalRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			pendingErr = fmt.Errorf("journal line %d: %w", lineNo, err)
+			continue
+		}
+
+		op := byID[rec.ID]
+		switch rec.Event {
+		case journalBegin:
+			op = &JournalOp{ID: rec.ID, Started: rec.Time, Server: rec.Server,
+				Database: rec.Database, Host: rec.Host, Hosts: rec.Hosts, Users: rec.Users}
+			if len(op.Hosts) == 0 {
+				op.Hosts = []string{rec.Host}
+			}
+			byID[rec.ID] = op
+			ops = append(ops, op)
+		case journalStep:
+			if op != nil {
+				op.Steps = append(op.Steps, rec)
+			}
+		case journalDone, journalRolledBack:
+			if op != nil {
+				op.Closed = true
+			}
+		}
+	}
+	return ops, sc.Err()
+}
+
+func readJournal(path string) ([]*JournalOp, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseJournal(f)
+}
+
+// incompleteOps returns the open operations for server section.
+func incompleteOps(ops []*JournalOp, section string) []*JournalOp {
+	var out []*JournalOp
+	for _, op := range ops {
+		if !op.Closed && op.Server == section {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// warnIncompleteJournal is run at startup so an interrupted create is
+// not forgotten.
+func warnIncompleteJournal(opts Options) {
+	ops, err := readJournal(opts.JournalPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Cannot read journal %s: %v\n", opts.JournalPath, err)
+		return
+	}
+	if n := len(incompleteOps(ops, serverSection(opts.Server))); n > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d interrupted operation(s) in %s; run 'mariadb-tool recover'\n",
+			n, opts.JournalPath)
+	}
+}
+
+/* ===============================
+   Recover
+================================= */
+
+const (
+	recoverCompleted  = "completed"
+	recoverRolledBack = "rolled_back"
+	recoverFailed     = "failed"
+)
+
+type RecoverResult struct {
+	ID       string    `json:"id"`
+	Started  time.Time `json:"started"`
+	Database string    `json:"database"`
+	Host     string    `json:"host"`
+	Hosts    []string  `json:"hosts,omitempty"`
+	Users    []string  `json:"users"`
+	Action   string    `json:"action"`
+	DryRun   bool      `json:"dry_run,omitempty"`
+	Message  string    `json:"message"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// recoverOp finishes op if everything up to the registry write happened,
+// and otherwise rolls back what the journal says was started. The
+// generated passwords are gone either way, so a completed create needs a
+// rotate before the accounts can be used.
+func recoverOp(ctx context.Context, db *sql.DB, opts Options, op *JournalOp) RecoverResult {
+	res := RecoverResult{ID: op.ID, Started: op.Started, Database: op.Database, Host: op.Host,
+		Users: op.Users, DryRun: opts.DryRun}
+	if len(op.Hosts) > 1 {
+		res.Hosts = op.Hosts
+	}
+	fail := func(err error) RecoverResult {
+		res.Action = recoverFailed
+		res.Error = err.Error()
+		return res
+	}
+
+	if op.hasStep(stepRegister, "", "") {
+		registered := true
+		for _, u := range op.Users {
+			for _, h := range op.Hosts {
+				ok, err := isRegistered(ctx, db, op.Database, u, h)
+				if err != nil {
+					return fail(err)
+				}
+				registered = registered && ok
+			}
+		}
+		if registered {
+			res.Action = recoverCompleted
+			res.Message = fmt.Sprintf("'%s' was created and registered; its password was never shown, run 'rotate %s'",
+				op.Database, op.Database)
+			if !op.hasStep(stepCreateDatabase, "", "") {
+				// An extra user from sync; rotate only knows owners.
+				res.Message = fmt.Sprintf("%s on '%s' was created and registered; its password was never shown, set one with ALTER USER",
+					strings.Join(accountList(op.Users, op.Hosts), ", "), op.Database)
+			}
+			if !opts.DryRun {
+				(&createJournal{path: opts.JournalPath, id: op.ID}).end(opts, journalDone, "recovered")
+			}
+			return res
+		}
+	}
+
+	var actions []string
+	for _, u := range op.Users {
+		for _, h := range op.Hosts {
+			if !op.hasStep(stepCreateUser, u, h) {
+				continue
+			}
+			exists, err := userExists(ctx, db, u, h)
+			if err != nil {
+				return fail(err)
+			}
+			if exists {
+				actions = append(actions, "DROP USER "+quoteUserHost(u, h))
+			}
+		}
+	}
+	if op.hasStep(stepCreateDatabase, "", "") {
+		exists, err := databaseExists(ctx, db, op.Database)
+		if err != nil {
+			return fail(err)
+		}
+		if exists {
+			stats, err := tableStats(ctx, db, op.Database)
+			if err != nil {
+				return fail(err)
+			}
+			// Tables mean someone started using it; never drop data.
+			if stats[op.Database].tables > 0 {
+				return fail(fmt.Errorf("database '%s' has %d table(s); not dropping it, resolve by hand",
+					op.Database, stats[op.Database].tables))
+			}
+			actions = append(actions, "DROP DATABASE "+quoteIdent(op.Database))
+		}
+	}
+
+	res.Action = recoverRolledBack
+	if len(actions) == 0 {
+		res.Message = fmt.Sprintf("Nothing left of '%s' on the server", op.Database)
+	} else {
+		res.Message = "Ran: " + strings.Join(actions, "; ")
+		if opts.DryRun {
+			res.Message = "Would run: " + strings.Join(actions, "; ")
+		}
+	}
+	if opts.DryRun {
+		return res
+	}
+
+	for _, q := range actions {
+		if err := execSQL(ctx, db, q); err != nil {
+			return fail(fmt.Errorf("%s: %w", q, err))
+		}
+	}
+	if op.hasStep(stepRegister, "", "") {
+		for _, u := range op.Users {
+			for _, h := range op.Hosts {
+				if err := unregisterAccount(ctx, db, op.Database, u, h); err != nil {
+					logError(opts.ErrorLogPath, fmt.Sprintf("Recover %s: unregister %s: %v",
+						op.ID, quoteUserHost(u, h), err))
+				}
+			}
+		}
+	}
+	(&createJournal{path: opts.JournalPath, id: op.ID}).end(opts, journalRolledBack, "recovered")
+	return res
+}
+
+// runRecover resolves every open operation for the selected server and
+// reports how many could not be resolved.
+func runRecover(db *sql.DB, opts Options) (int, error) {
+	lock, err := lockJournal(opts.JournalPath, true)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.unlock()
+
+	ops, err := readJournal(opts.JournalPath)
+	if err != nil {
+		return 0, fmt.Errorf("read journal %s: %w", opts.JournalPath, err)
+	}
+
+	results := []RecoverResult{}
+	failed := 0
+	for _, op := range incompleteOps(ops, serverSection(opts.Server)) {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		res := recoverOp(ctx, db, opts, op)
+		cancel()
+		if res.Action == recoverFailed {
+			failed++
+			logError(opts.ErrorLogPath, fmt.Sprintf("Recover %s (%s) failed: %s", op.ID, op.Database, res.Error))
+		}
+		results = append(results, res)
+	}
+
+	if opts.Output != outputText {
+		return failed, writeJSON(os.Stdout, results)
+	}
+	if len(results) == 0 {
+		fmt.Println("✅ No interrupted operations.")
+		return 0, nil
+	}
+	for _, r := range results {
+		hosts := r.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{r.Host}
+		}
+		users := strings.Join(accountList(r.Users, hosts), ", ")
+		switch r.Action {
+		case recoverFailed:
+			fmt.Printf("❌ %s (started %s, %s): %s\n", r.Database, r.Started.Format(time.DateTime), users, r.Error)
+		case recoverCompleted:
+			fmt.Printf("✅ %s (started %s, %s): %s\n", r.Database, r.Started.Format(time.DateTime), users, r.Message)
+		default:
+			fmt.Printf("↩️  %s (started %s, %s): %s\n", r.Database, r.Started.Format(time.DateTime), users, r.Message)
+		}
+	}
+	return failed, nil
+}
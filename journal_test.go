@@ -0,0 +1,170 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestCreateJournalRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "journal.jsonl")
+	opts := Options{JournalPath: path, UserHost: "localhost"}
+
+	done, err := beginCreateJournal(opts, "shop", []string{"shop"}, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = done.step(stepCreateDatabase, "", "")
+	done.end(opts, journalDone, "")
+
+	cut, err := beginCreateJournal(opts, "blog", []string{"blog", "blog_ro"}, []string{"localhost", "10.0.0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = cut.step(stepCreateDatabase, "", "")
+	_ = cut.step(stepCreateUser, "blog", "localhost")
+
+	other, _ := beginCreateJournal(Options{JournalPath: path, Server: "prod"}, "wiki", []string{"wiki"}, []string{"localhost"})
+	_ = other.step(stepCreateDatabase, "", "")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("journal mode %v, want 0600", info.Mode().Perm())
+	}
+
+	ops, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("got %d operations", len(ops))
+	}
+	open := incompleteOps(ops, serverSection(""))
+	if len(open) != 1 || open[0].Database != "blog" || open[0].Host != "localhost" {
+		t.Fatalf("unexpected open operations: %+v", open)
+	}
+	if !slices.Equal(open[0].Hosts, []string{"localhost", "10.0.0.5"}) {
+		t.Fatalf("unexpected hosts: %q", open[0].Hosts)
+	}
+	if !open[0].hasStep(stepCreateUser, "blog", "localhost") || open[0].hasStep(stepCreateUser, "blog", "10.0.0.5") ||
+		open[0].hasStep(stepCreateUser, "blog_ro", "") {
+		t.Fatalf("unexpected steps: %+v", open[0].Steps)
+	}
+	if len(incompleteOps(ops, serverSection("prod"))) != 1 {
+		t.Fatal("operation on another server not kept apart")
+	}
+}
+
+func TestReadJournalMissingFile(t *testing.T) {
+	ops, err := readJournal(filepath.Join(t.TempDir(), "none.jsonl"))
+	if err != nil || ops != nil {
+		t.Fatalf("got %v, %v", ops, err)
+	}
+}
+
+func TestParseJournalTornLine(t *testing.T) {
+	in := `{"id":"a","event":"begin","server":"mariadb","database":"shop","users":["shop"]}
+{"id":"a","event":"step","step":"create_database"}
+{"id":"a","event":"st`
+	ops, err := parseJournal(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("torn last line should be ignored: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Closed || len(ops[0].Steps) != 1 {
+		t.Fatalf("unexpected operations: %+v", ops)
+	}
+	// Entries without hosts fall back to the single host.
+	if len(ops[0].Hosts) != 1 || !ops[0].hasStep(stepCreateDatabase, "", "localhost") {
+		t.Fatalf("unexpected hosts or steps: %+v", ops[0])
+	}
+
+	in = `{"id":"a","event":"begin"}
+garbage
+{"id":"a","event":"done"}`
+	if _, err := parseJournal(strings.NewReader(in)); err == nil {
+		t.Fatal("expected an error for a corrupt line in the middle")
+	}
+}
+
+func TestLockJournal(t *testing.T) {
+	if !haveFlock {
+		t.Skip("no flock on this platform")
+	}
+	path := filepath.Join(t.TempDir(), "state", "journal.jsonl")
+
+	// Two creates (e.g. -parallel workers) share the lock.
+	a, err := lockJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := lockJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// recover must not run while either is active.
+	if _, err := lockJournal(path, true); !errors.Is(err, errJournalBusy) {
+		t.Fatalf("exclusive lock during create: %v", err)
+	}
+	a.unlock()
+	if _, err := lockJournal(path, true); !errors.Is(err, errJournalBusy) {
+		t.Fatalf("exclusive lock during create: %v", err)
+	}
+	b.unlock()
+
+	rec, err := lockJournal(path, true)
+	if err != nil {
+		t.Fatalf("exclusive lock when idle: %v", err)
+	}
+	rec.unlock()
+
+	if l, err := lockJournal("", true); l != nil || err != nil {
+		t.Fatalf("disabled journal: %v, %v", l, err)
+	}
+}
+
+func TestCreateOutcomeUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"database exists", &mysql.MySQLError{Number: 1007, Message: "Can't create database 'blog'; database exists"}, false},
+		{"access denied", fmt.Errorf("create: %w", &mysql.MySQLError{Number: 1044}), false},
+		{"timeout", context.DeadlineExceeded, true},
+		{"bad conn", driver.ErrBadConn, true},
+		{"invalid conn", mysql.ErrInvalidConn, true},
+		{"network", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, true},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := createOutcomeUnknown(tt.err); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
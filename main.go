@@ -14,18 +14,40 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 )
 
 func main() {
 	opts := parseFlags()
+	section := serverSection(opts.Server)
 
-	// Init or missing config => init (at XDG default unless overridden)
-	if opts.Init || !configFileExists(opts.ConfigPath) {
-		if err := initializeConfig(opts.ConfigPath); err != nil {
+	if opts.Command == cmdServers {
+		if err := printServers(opts); err != nil {
+			log.Fatalf("Error reading config: %v", err)
+		}
+		return
+	}
+
+	if opts.Command != cmdRecover && !opts.Init {
+		warnIncompleteJournal(opts)
+	}
+
+	optionCfg, err := loadOptionFiles(opts)
+	if err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Error reading option files: %v", err))
+		log.Fatalf("Error reading option files: %v", err)
+	}
+
+	// Init or missing config => init (at XDG default unless overridden).
+	// Credentials from ~/.my.cnf and friends make config.ini optional.
+	if opts.Init || (!configFileExists(opts.ConfigPath) && optionCfg["username"] == "") {
+		if err := initializeConfig(opts, section); err != nil {
 			logError(opts.ErrorLogPath, fmt.Sprintf("Config init failed: %v", err))
 			log.Fatalf("Config init failed: %v", err)
 		}
@@ -34,12 +56,46 @@ func main() {
 		}
 	}
 
-	cfg, err := loadConfig(opts.ConfigPath, "mariadb")
+	cfg, err := loadConnectionConfig(opts.ConfigPath, section, optionCfg)
 	if err != nil {
 		logError(opts.ErrorLogPath, fmt.Sprintf("Error reading config (%s): %v", opts.ConfigPath, err))
 		log.Fatalf("Error reading config: %v", err)
 	}
 
+	if err := applyServerDefaults(&opts, cfg, explicitFlags()); err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Error reading config (%s): %v", opts.ConfigPath, err))
+		log.Fatalf("Error reading config: %v", err)
+	}
+
+	// Checked here, as the config may also give several hosts. CIDR hosts
+	// become ip/netmask once, so every command sees the MariaDB form.
+	opts.UserHost = strings.Join(userHosts(opts.UserHost), ",")
+	if len(userHosts(opts.UserHost)) > 1 &&
+		(opts.Command == cmdDelete || opts.Command == cmdAdopt || opts.Command == cmdSync) {
+		fmt.Fprintf(os.Stderr, "%s takes a single -user-host\n", opts.Command)
+		os.Exit(exitUsage)
+	}
+
+	// Checked before connecting, like profiles; plans pick up the policy
+	// at apply time since they never hold passwords.
+	opts.PasswordPolicy, err = loadPasswordPolicy(opts.ConfigPath)
+	if err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Invalid password policy: %v", err))
+		log.Fatalf("Invalid password policy: %v", err)
+	}
+
+	if opts.Command == cmdCreate || opts.Command == cmdPlan || opts.Command == cmdAudit {
+		opts.Privileges, err = loadPrivilegeProfile(opts.ConfigPath, opts.Profile)
+		if err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Invalid privilege profile: %v", err))
+			log.Fatalf("Invalid privilege profile: %v", err)
+		}
+		// A profile's auth= applies unless -auth was given.
+		if !explicitFlags()["auth"] {
+			opts.Auth = opts.Privileges.Auth
+		}
+	}
+
 	db, err := openDB(cfg, opts.Timeout)
 	if err != nil {
 		logError(opts.ErrorLogPath, fmt.Sprintf("DB connect failed: %v", err))
@@ -47,24 +103,214 @@ func main() {
 	}
 	defer db.Close()
 
+	ctx, stop := interruptContext()
+	defer stop()
+
+	switch opts.Command {
+	case cmdPlan:
+		runPlanCommand(db, opts, section, cfg)
+		return
+	case cmdApply:
+		runApplyCommand(ctx, db, opts, section, cfg)
+		return
+	case cmdList, cmdInspect, cmdShow:
+		if err := runListCommand(db, opts); err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("%s failed: %v", opts.Command, err))
+			log.Fatalf("Failed: %v", err)
+		}
+		return
+	case cmdAudit:
+		n, err := runAudit(db, opts)
+		if err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Audit failed: %v", err))
+			log.Fatalf("Audit failed: %v", err)
+		}
+		if n > 0 {
+			os.Exit(exitFindings)
+		}
+		return
+	case cmdRecover:
+		failed, err := runRecover(db, opts)
+		if err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Recover failed: %v", err))
+			log.Fatalf("Recover failed: %v", err)
+		}
+		if failed > 0 {
+			os.Exit(exitPartial)
+		}
+		return
+	case cmdSecurityReport:
+		r, err := runSecurityReport(db, opts, section, serverAddress(cfg))
+		if err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Security report failed: %v", err))
+			log.Fatalf("Security report failed: %v", err)
+		}
+		if r.Summary.High > 0 {
+			os.Exit(exitFindings)
+		}
+		return
+	case cmdSync:
+		sum, err := runSync(ctx, db, opts)
+		if err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("Sync failed (%s): %v", opts.Manifest, err))
+			log.Fatalf("Sync failed: %v", err)
+		}
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
+		if sum.Refused > 0 || sum.Failed > 0 {
+			os.Exit(exitPartial)
+		}
+		return
+	}
+
+	var fn itemFunc
+	switch opts.Command {
+	case cmdCreate:
+		fn = processDatabase
+	case cmdDelete:
+		fn = deprovisionDatabase
+	case cmdRotate:
+		fn = rotatePassword
+	case cmdAdopt:
+		fn = adoptDatabase
+	}
+
 	switch {
-	case opts.CreateName != "":
-		name := strings.TrimSpace(opts.CreateName)
-		res, err := processDatabase(db, opts, name)
+	case opts.Target != "":
+		name := strings.TrimSpace(opts.Target)
+		res, err := fn(ctx, db, opts, name)
+		if interrupted(ctx, err) {
+			fmt.Fprintf(os.Stderr, "Interrupted before '%s' was started\n", name)
+			os.Exit(exitInterrupted)
+		}
 		if err != nil {
-			logError(opts.ErrorLogPath, fmt.Sprintf("Create failed (%s): %v", name, err))
-			log.Fatalf("Failed: %v", err)
+			logError(opts.ErrorLogPath, fmt.Sprintf("%s failed (%s): %v", commandLabel(opts.Command), name, err))
+		}
+		if opts.Output == outputText {
+			if err != nil {
+				log.Fatalf("Failed: %v", err)
+			}
+			printResult(opts, res)
+			return
+		}
+		_ = writeJSON(os.Stdout, newResultRecord(opts.Command, 0, name, res, err))
+		if err != nil {
+			os.Exit(exitError)
 		}
-		printResult(opts, res)
 
 	case opts.FileList != "":
-		if err := processFile(db, opts, opts.FileList); err != nil {
+		rep := newReporter(opts, opts.FileList)
+		if err := processFile(ctx, db, opts, opts.FileList, fn, rep); err != nil {
 			logError(opts.ErrorLogPath, fmt.Sprintf("Batch failed (%s): %v", opts.FileList, err))
 			log.Fatalf("Batch failed: %v", err)
 		}
+		if code := batchExitCode(rep.summary()); code != exitOK {
+			os.Exit(code)
+		}
 
 	default:
 		flag.Usage()
+		os.Exit(exitUsage)
+	}
+}
+
+// toolVersion is recorded in the registry with every account. Release
+// builds set it with -ldflags "-X main.toolVersion=<version>"; anything
+// else is an unreleased build.
+var toolVersion = "dev"
+
+const (
+	cmdCreate  = "create"
+	cmdDelete  = "delete"
+	cmdRotate  = "rotate"
+	cmdServers = "servers"
+	cmdPlan    = "plan"
+	cmdApply   = "apply"
+	cmdSync    = "sync"
+	cmdAdopt   = "adopt"
+	cmdList    = "list"
+	cmdInspect = "inspect"
+	cmdShow    = "show"
+	cmdAudit   = "audit"
+
+	cmdRecover = "recover"
+
+	cmdSecurityReport = "security-report"
+)
+
+func commandLabel(cmd string) string {
+	switch cmd {
+	case cmdDelete:
+		return "Delete"
+	case cmdRotate:
+		return "Rotate"
+	case cmdAdopt:
+		return "Adopt"
+	default:
+		return "Create"
+	}
+}
+
+// explicitFlags reports which flags were given on the command line, so
+// config defaults never override them.
+func explicitFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// hostListFlag joins repeated -user-host flags with commas. The first one
+// replaces the default.
+type hostListFlag struct {
+	v   *string
+	set bool
+}
+
+func (f *hostListFlag) String() string {
+	if f.v == nil {
+		return ""
+	}
+	return *f.v
+}
+
+func (f *hostListFlag) Set(s string) error {
+	if f.set {
+		*f.v += "," + s
+	} else {
+		*f.v = s
+		f.set = true
+	}
+	return nil
+}
+
+// splitCommand separates an optional leading subcommand from the flags,
+// so both "delete -f list.txt" and the classic "-c name" forms work.
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// parseArgs parses flags wherever they appear, so "rotate shop -dry-run"
+// does not silently drop -dry-run, and returns the positional arguments.
+// Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(pos, rest...), nil
+		}
+		if len(rest) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
 	}
 }
 
@@ -73,20 +319,33 @@ func parseFlags() Options {
 	if err != nil || validateNotEmptyPaths(dp) != nil {
 		// Last-resort fallback if we can't resolve XDG paths
 		dp = DefaultPaths{
-			ConfigPath: "config.ini",
-			ErrorLog:   "error.log",
-			CSVPath:    "accounts.csv",
+			ConfigPath:  "config.ini",
+			ErrorLog:    "error.log",
+			CSVPath:     "accounts.csv",
+			Journal:     "journal.jsonl",
+			Checkpoints: "checkpoints",
 		}
 	}
 
 	var opts Options
 
 	flag.StringVar(&opts.CreateName, "c", "", "Create single database/user (name)")
+	flag.StringVar(&opts.DeleteName, "d", "", "Delete single database/user (name)")
 	flag.StringVar(&opts.FileList, "f", "", "Batch processing from file (one name per line)")
 	flag.BoolVar(&opts.Init, "i", false, "Initialize configuration")
+	flag.BoolVar(&opts.NonInteractive, "non-interactive", false, "With -i: take values from flags/environment instead of prompting")
+	flag.StringVar(&opts.InitUser, "init-user", "", "With -i -non-interactive: admin username (env "+envInitUser+", default root)")
+	flag.StringVar(&opts.InitHost, "init-host", "", "With -i -non-interactive: server hostname (env "+envInitHost+", default localhost)")
+	flag.StringVar(&opts.InitPort, "init-port", "", "With -i -non-interactive: server port (env "+envInitPort+", default 3306)")
+	flag.BoolVar(&opts.InitPasswordStdin, "init-password-stdin", false, "With -i -non-interactive: read the password from stdin (else env "+envInitPassword+")")
+	flag.BoolVar(&opts.InitNoVerify, "init-no-verify", false, "With -i: write config without testing the connection")
 
 	flag.StringVar(&opts.ConfigPath, "config", dp.ConfigPath, "Path to config.ini")
-	flag.StringVar(&opts.UserHost, "user-host", "localhost", "Host part for created user (e.g. localhost)")
+	flag.StringVar(&opts.DefaultsFile, "defaults-file", "", "Read client settings only from this MariaDB option file")
+	flag.BoolVar(&opts.NoDefaults, "no-defaults", false, "Do not read MariaDB option files (~/.my.cnf, /etc/my.cnf, ...)")
+	flag.StringVar(&opts.Server, "server", "", "Server profile: use [mariadb:<name>] instead of [mariadb] from config")
+	opts.UserHost = "localhost"
+	flag.Var(&hostListFlag{v: &opts.UserHost}, "user-host", "Host part for created user (e.g. localhost); comma-separated or repeated for several")
 	flag.BoolVar(&opts.AllowWildcardHost, "allow-wildcard-host", false, "Allow host wildcards in -user-host (%, _)")
 
 	flag.DurationVar(&opts.Timeout, "timeout", defaultTimeout, "Timeout per DB operation (e.g. 6s, 10s)")
@@ -95,24 +354,240 @@ func parseFlags() Options {
 	flag.StringVar(&opts.CSVPath, "csv", dp.CSVPath, "CSV output path (used with -export-csv)")
 
 	flag.StringVar(&opts.ErrorLogPath, "error-log", dp.ErrorLog, "Error log path")
+	flag.StringVar(&opts.JournalPath, "journal", dp.Journal, "Journal of create steps, used by recover")
+	flag.BoolVar(&opts.Resume, "resume", false, "With -f: skip lines an earlier run of the same file completed")
+	flag.StringVar(&opts.CheckpointDir, "checkpoint-dir", dp.Checkpoints, "Directory for batch checkpoints (used by -resume)")
 	flag.BoolVar(&opts.DryRun, "dry-run", false, "Show what would be done, but do not execute changes")
 
 	flag.BoolVar(&opts.Normalize, "normalize", true, "Normalize input names (e.g. hardhq.com -> hardhq_com)")
+	flag.BoolVar(&opts.Yes, "yes", false, "Do not ask for confirmation before destructive operations")
+	flag.StringVar(&opts.Profile, "profile", defaultPrivilegeProfile, "Privilege profile for created users (owner, readwrite, readonly, migrator or [profile:<name>] in config)")
+	flag.StringVar(&opts.Charset, "charset", "", "Character set for created databases (default: server default)")
+	flag.StringVar(&opts.Collation, "collation", "", "Collation for created databases (e.g. utf8mb4_uca1400_ai_ci)")
+	flag.Func("auth", "Authentication for created users: ed25519, unix_socket, mysql_native_password or a list tried in order (\"unix_socket,ed25519\")", func(s string) error {
+		a, err := parseAuth(s)
+		opts.Auth = a
+		return err
+	})
+	flag.BoolVar(&opts.ReadOnlyUser, "readonly-user", false, "Also create (adopt: also register) a SELECT-only companion user <name>_ro")
+	flag.BoolVar(&opts.Disambiguate, "disambiguate", false, "Batch create: add a hash suffix to names that collide after normalization instead of aborting")
+	flag.IntVar(&opts.Parallel, "parallel", 1, "Process batch lines with N concurrent workers (output stays in input order)")
+	flag.StringVar(&opts.Output, "output", outputText, "Output format: text, json, jsonl (jsonl: one object per batch line), markdown (security-report only)")
+	flag.StringVar(&opts.PlanOut, "plan-out", "", "With plan: write the plan to this file instead of stdout")
+	flag.StringVar(&opts.Owner, "owner", "", "Owner recorded in the registry for created databases (e.g. team name)")
+	flag.StringVar(&opts.Ticket, "ticket", "", "Ticket/change reference recorded in the registry for created databases")
+	flag.StringVar(&opts.Manifest, "manifest", "", "With sync: YAML or JSON manifest of the desired databases and users")
+	flag.BoolVar(&opts.Apply, "apply", false, "With sync: execute the changes instead of only showing them")
+	flag.BoolVar(&opts.Prune, "prune", false, "With sync: also drop tool-managed databases that are not in the manifest")
 
 	flag.Usage = func() {
 		fmt.Println("Usage:")
-		fmt.Println("  -c <name>      Create single database/user")
-		fmt.Println("  -f <file.txt>  Batch processing from file (one name per line)")
-		fmt.Println("  -i             Initialize configuration")
+		fmt.Println("  -c <name>                Create single database/user")
+		fmt.Println("  -d <name>                Delete single database/user")
+		fmt.Println("  -f <file.txt>            Batch processing from file (one name per line)")
+		fmt.Println("  delete <name>            Delete single database/user")
+		fmt.Println("  delete -f <file.txt>     Batch delete from file")
+		fmt.Println("  rotate <name>            Rotate password of existing user")
+		fmt.Println("  rotate -f <file.txt>     Batch password rotation from file")
+		fmt.Println("  plan -f <file.txt>       Write a reviewable plan for a batch create (-plan-out <file>)")
+		fmt.Println("  plan <name>              Write a plan for a single create")
+		fmt.Println("  apply <plan.json>        Re-verify the server against a plan, then execute it")
+		fmt.Println("  sync -manifest <file>    Show changes needed to match a manifest (-apply to execute)")
+		fmt.Println("  list                     List databases with charset, size, users and registry state")
+		fmt.Println("  inspect <name>           Show a database, its users on all hosts and their grants")
+		fmt.Println("  show [<name>]            List the registry, or one database's registry rows and live state")
+		fmt.Println("  audit                    Report orphans, grant drift and wildcard hosts (exit 4 on findings)")
+		fmt.Println("  security-report          Report risky server-wide accounts and settings (-output markdown)")
+		fmt.Println("  adopt <name>             Register an existing, hand-made database/user pair")
+		fmt.Println("  adopt -f <file.txt>      Batch adopt from file")
+		fmt.Println("  recover                  Finish or roll back creates interrupted by a crash (see journal)")
+		fmt.Println("  servers                  List server profiles in config")
+		fmt.Println("  -i                       Initialize configuration (with -server: add/replace that profile)")
 		fmt.Println("")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 	}
 
-	flag.Parse()
+	cmd, args := splitCommand(os.Args[1:])
+	positional, _ := parseArgs(flag.CommandLine, args)
+	if len(positional) > 1 || (cmd == "" && len(positional) > 0) {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", positional[len(positional)-1])
+		os.Exit(exitUsage)
+	}
+
+	switch cmd {
+	case "", cmdCreate:
+		opts.Command = cmdCreate
+	case cmdDelete, cmdRotate, cmdServers, cmdPlan, cmdApply, cmdSync, cmdAdopt, cmdList, cmdInspect, cmdShow, cmdAudit, cmdSecurityReport, cmdRecover:
+		opts.Command = cmd
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if opts.Output == outputMarkdown {
+		if opts.Command != cmdSecurityReport {
+			fmt.Fprintln(os.Stderr, "-output markdown is only supported by security-report")
+			os.Exit(exitUsage)
+		}
+	} else if err := validateOutputFormat(opts.Output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	opts.Charset = strings.ToLower(strings.TrimSpace(opts.Charset))
+	opts.Collation = strings.ToLower(strings.TrimSpace(opts.Collation))
+	if opts.Charset != "" {
+		if err := validateCharset(opts.Charset); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+	}
+	if opts.Collation != "" {
+		if err := validateCollation(opts.Collation); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	if opts.Parallel < 1 {
+		fmt.Fprintln(os.Stderr, "-parallel must be at least 1")
+		os.Exit(exitUsage)
+	}
+	// Confirmation prompts from several workers would interleave.
+	if opts.Parallel > 1 && cmd == cmdDelete && !opts.Yes && !opts.DryRun {
+		fmt.Fprintln(os.Stderr, "delete with -parallel requires -yes or -dry-run")
+		os.Exit(exitUsage)
+	}
+
+	// -c and -d are shortcuts; they cannot be combined with each other or a subcommand.
+	if (opts.CreateName != "" && opts.DeleteName != "") ||
+		(cmd != "" && (opts.CreateName != "" || opts.DeleteName != "")) {
+		fmt.Fprintln(os.Stderr, "-c, -d and subcommands are mutually exclusive")
+		os.Exit(exitUsage)
+	}
+
+	switch {
+	case opts.DeleteName != "":
+		opts.Command = cmdDelete
+		opts.Target = opts.DeleteName
+	case opts.CreateName != "":
+		opts.Target = opts.CreateName
+	case len(positional) > 0:
+		opts.Target = positional[0]
+	}
+
+	if len(opts.Owner) > 255 || len(opts.Ticket) > 255 {
+		fmt.Fprintln(os.Stderr, "-owner and -ticket are limited to 255 characters")
+		os.Exit(exitUsage)
+	}
+	if opts.Command == cmdSync && opts.Manifest == "" {
+		fmt.Fprintln(os.Stderr, "sync requires -manifest <file>")
+		os.Exit(exitUsage)
+	}
+	if opts.Apply && opts.DryRun {
+		fmt.Fprintln(os.Stderr, "-apply and -dry-run are mutually exclusive")
+		os.Exit(exitUsage)
+	}
+	if (opts.Apply || opts.Prune) && opts.Command != cmdSync {
+		fmt.Fprintln(os.Stderr, "-apply and -prune are only valid with sync")
+		os.Exit(exitUsage)
+	}
+	if opts.Resume && (opts.FileList == "" || opts.Command == cmdPlan) {
+		fmt.Fprintln(os.Stderr, "-resume requires a batch run with -f")
+		os.Exit(exitUsage)
+	}
+	if opts.Command == cmdApply && (opts.Target == "" || opts.FileList != "") {
+		fmt.Fprintln(os.Stderr, "apply takes a plan file: apply <plan.json>")
+		os.Exit(exitUsage)
+	}
+
 	return opts
 }
 
+// runListCommand handles the read-only list, inspect and show commands.
+func runListCommand(db *sql.DB, opts Options) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	if opts.Command == cmdShow {
+		if opts.Target == "" {
+			entries, err := registryEntries(ctx, db, "")
+			if err != nil {
+				return err
+			}
+			return printRegistryList(os.Stdout, opts, entries)
+		}
+		s, err := showRegistered(ctx, db, opts, opts.Target)
+		if err != nil {
+			return err
+		}
+		return printRegistryShow(os.Stdout, opts, s)
+	}
+
+	if opts.Command == cmdList {
+		dbs, err := listDatabases(ctx, db)
+		if err != nil {
+			return err
+		}
+		return printDatabaseList(os.Stdout, opts, dbs)
+	}
+
+	if opts.Target == "" {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	_, name, err := resolveName(opts, opts.Target)
+	if err != nil {
+		return err
+	}
+	r, err := inspectDatabase(ctx, db, name)
+	if err != nil {
+		return err
+	}
+	return printInspect(os.Stdout, opts, r)
+}
+
+// runPlanCommand writes the plan and a short summary on stderr. Invalid
+// entries make the exit status non-zero, since apply will refuse the plan.
+func runPlanCommand(db *sql.DB, opts Options, section string, cfg map[string]string) {
+	if opts.Target == "" && opts.FileList == "" {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	p, err := runPlan(db, opts, section, serverAddress(cfg))
+	if err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Plan failed: %v", err))
+		log.Fatalf("Plan failed: %v", err)
+	}
+
+	create, skip, invalid := p.counts()
+	dest := "stdout"
+	if opts.PlanOut != "" {
+		dest = opts.PlanOut
+	}
+	fmt.Fprintf(os.Stderr, "Plan written to %s: %d to create, %d to skip, %d invalid\n",
+		dest, create, skip, invalid)
+	if invalid > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+func runApplyCommand(ctx context.Context, db *sql.DB, opts Options, section string, cfg map[string]string) {
+	// Results are reported as a create run.
+	opts.Command = cmdCreate
+	rep := newReporter(opts, opts.Target)
+	if err := runApply(ctx, db, opts, opts.Target, section, serverAddress(cfg), rep); err != nil {
+		logError(opts.ErrorLogPath, fmt.Sprintf("Apply failed (%s): %v", opts.Target, err))
+		log.Fatalf("Apply failed: %v", err)
+	}
+	if code := batchExitCode(rep.summary()); code != exitOK {
+		os.Exit(code)
+	}
+}
+
 func printResult(opts Options, res *CreateResult) {
 	if res == nil {
 		return
@@ -127,11 +602,91 @@ func printResult(opts Options, res *CreateResult) {
 		fmt.Printf("⚠️  %s\n", res.Message)
 	case StatusDryRun:
 		fmt.Printf("✅ DRY-RUN OK: %s\n", res.Name)
-	case StatusCreated:
-		fmt.Printf("✅ Success: %s created.\n", res.Name)
-		fmt.Printf("   Username: %s\n   Host:     %s\n   Password: %s\n", res.Username, res.UserHost, res.Password)
+		if res.Message != "" {
+			fmt.Printf("   %s\n", res.Message)
+		}
+	case StatusDeleted:
+		users := []string{res.Username}
+		if res.ReadOnlyUsername != "" {
+			users = append(users, res.ReadOnlyUsername)
+		}
+		fmt.Printf("✅ Deleted: %s (database and user %s dropped).\n",
+			res.Name, quoteUsersHost(users, res.UserHost))
+		if res.Message != "" {
+			fmt.Printf("   %s\n", res.Message)
+		}
+	case StatusAdopted:
+		fmt.Printf("✅ Adopted: %s\n", res.Message)
+		if res.Profile != "" {
+			fmt.Printf("   Profile:  %s\n", res.Profile)
+		}
+	case StatusCreated, StatusRotated:
+		if res.Status == StatusRotated {
+			fmt.Printf("✅ Rotated: new password for %s.\n", res.Name)
+		} else {
+			fmt.Printf("✅ Success: %s created.\n", res.Name)
+		}
+		host := res.UserHost
+		if len(res.UserHosts) > 1 {
+			host = strings.Join(res.UserHosts, ", ")
+		}
+		password := res.Password
+		if password == "" {
+			password = "(none, authenticates via " + res.Auth + ")"
+		}
+		fmt.Printf("   Username: %s\n   Host:     %s\n   Password: %s\n", res.Username, host, password)
+		if res.Profile != "" {
+			fmt.Printf("   Profile:  %s\n", res.Profile)
+		}
+		if res.Auth != "" {
+			fmt.Printf("   Auth:     %s\n", res.Auth)
+		}
+		if res.ReadOnlyUsername != "" && res.ReadOnlyPassword == "" {
+			fmt.Printf("   Read-only username: %s\n", res.ReadOnlyUsername)
+		} else if res.ReadOnlyUsername != "" {
+			fmt.Printf("   Read-only username: %s\n   Read-only password: %s\n",
+				res.ReadOnlyUsername, res.ReadOnlyPassword)
+		}
 		if opts.ExportCSV && res.CSVExported {
 			fmt.Printf("   Exported:  %s\n", opts.CSVPath)
 		}
 	}
 }
+
+type serverInfo struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+}
+
+// printServers lists the server profiles without ever showing passwords.
+func printServers(opts Options) error {
+	sections, err := parseINI(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	var servers []serverInfo
+	for _, name := range serverNames(sections) {
+		sec := sections[serverSection(name)]
+		servers = append(servers, serverInfo{
+			Name:     name,
+			Hostname: sec["hostname"],
+			Port:     sec["port"],
+			Username: sec["username"],
+		})
+	}
+
+	if opts.Output != outputText {
+		if servers == nil {
+			servers = []serverInfo{}
+		}
+		return writeJSON(os.Stdout, servers)
+	}
+
+	for _, s := range servers {
+		fmt.Printf("%-20s %s@%s:%s\n", s.Name, s.Username, s.Hostname, s.Port)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/* ===============================
+   MariaDB option files (my.cnf)
+================================= */
+
+// optionGroups are the option-file groups read for client settings, the
+// same ones the mariadb command-line client reads.
+var optionGroups = map[string]bool{
+	"client":         true,
+	"client-server":  true,
+	"client-mariadb": true,
+}
+
+// maxIncludeDepth guards against !include loops.
+const maxIncludeDepth = 10
+
+// defaultOptionFiles lists the option files in the order the mariadb
+// client reads them on Unix. Later files override earlier ones.
+func defaultOptionFiles() []string {
+	files := []string{"/etc/my.cnf", "/etc/mysql/my.cnf"}
+	if v := strings.TrimSpace(os.Getenv("MYSQL_HOME")); v != "" {
+		files = append(files, filepath.Join(v, "my.cnf"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".my.cnf"))
+	}
+	return files
+}
+
+// loadOptionFiles reads client settings from MariaDB option files and
+// returns them translated to config.ini keys. -defaults-file replaces the
+// default list and must exist; default files that are missing are skipped.
+func loadOptionFiles(opts Options) (map[string]string, error) {
+	raw := make(map[string]string)
+	if opts.NoDefaults {
+		return raw, nil
+	}
+
+	files := defaultOptionFiles()
+	if opts.DefaultsFile != "" {
+		files = []string{opts.DefaultsFile}
+	}
+
+	for _, f := range files {
+		err := readOptionFile(f, raw, 0)
+		if errors.Is(err, os.ErrNotExist) && opts.DefaultsFile == "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return translateOptions(raw), nil
+}
+
+// readOptionFile applies the options of the client groups in path to out,
+// in file order, following !include and !includedir directives.
+func readOptionFile(path string, out map[string]string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: !include nested too deeply", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	inGroup := false
+	lineNo := 0
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			directive, arg, _ := strings.Cut(line, " ")
+			arg = strings.TrimSpace(arg)
+			if arg != "" && !filepath.IsAbs(arg) {
+				arg = filepath.Join(filepath.Dir(path), arg)
+			}
+			// %v, not %w: a missing include is an error even when the
+			// including file itself is optional.
+			switch directive {
+			case "!include":
+				if err := readOptionFile(arg, out, depth+1); err != nil {
+					return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+				}
+			case "!includedir":
+				if err := readOptionDir(arg, out, depth+1); err != nil {
+					return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+				}
+			default:
+				return fmt.Errorf("%s:%d: unknown directive %s", path, lineNo, directive)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return fmt.Errorf("%s:%d: malformed group header", path, lineNo)
+			}
+			inGroup = optionGroups[strings.ToLower(strings.TrimSpace(line[1:end]))]
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		key = normalizeOptionKey(key)
+		if key == "" {
+			continue
+		}
+		if !hasValue {
+			// Bare option, e.g. "ssl-verify-server-cert" or "skip-ssl".
+			// A bare "password" makes the client prompt, so any password
+			// read so far no longer applies; other bare options need a
+			// value and are ignored.
+			name, v := key, "1"
+			switch {
+			case strings.HasPrefix(key, "skip-"):
+				name, v = strings.TrimPrefix(key, "skip-"), "0"
+			case strings.HasPrefix(key, "disable-"):
+				name, v = strings.TrimPrefix(key, "disable-"), "0"
+			case strings.HasPrefix(key, "enable-"):
+				name = strings.TrimPrefix(key, "enable-")
+			}
+			switch {
+			case booleanOptions[name]:
+				out[name] = v
+			case key == "password":
+				delete(out, "password")
+			}
+			continue
+		}
+		v, err := parseOptionValue(value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		out[key] = v
+	}
+
+	return sc.Err()
+}
+
+// booleanOptions are the switches translateOptions reads that may be
+// given without a value.
+var booleanOptions = map[string]bool{
+	"ssl":                    true,
+	"ssl-verify-server-cert": true,
+}
+
+// readOptionDir reads every *.cnf file in dir in name order.
+func readOptionDir(dir string, out map[string]string, depth int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".cnf") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := readOptionFile(filepath.Join(dir, n), out, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeOptionKey lower-cases the key and treats '_' like '-', as the
+// MariaDB option parser does.
+func normalizeOptionKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.TrimPrefix(key, "--")
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// parseOptionValue handles quoted values with backslash escapes and
+// strips trailing "# comments" from unquoted ones.
+func parseOptionValue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+
+	q := s[0]
+	if q != '"' && q != '\'' {
+		if i := strings.Index(s, "#"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		return unescapeOptionValue(s), nil
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			sb.WriteString(optionEscape(s[i]))
+		case c == q:
+			rest := strings.TrimSpace(s[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", fmt.Errorf("unexpected text after quoted value: %s", rest)
+			}
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated quoted value")
+}
+
+func unescapeOptionValue(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			sb.WriteString(optionEscape(s[i]))
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func optionEscape(c byte) string {
+	switch c {
+	case 'b':
+		return "\b"
+	case 't':
+		return "\t"
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 's':
+		return " "
+	default:
+		return string(c)
+	}
+}
+
+// translateOptions maps option-file keys to the keys used in config.ini.
+func translateOptions(raw map[string]string) map[string]string {
+	cfg := make(map[string]string)
+	rename := map[string]string{
+		"user":     "username",
+		"password": "password",
+		"host":     "hostname",
+		"port":     "port",
+		"socket":   "socket",
+		"ssl-ca":   "ssl-ca",
+		"ssl-cert": "ssl-cert",
+		"ssl-key":  "ssl-key",
+	}
+	for from, to := range rename {
+		if v, ok := raw[from]; ok {
+			cfg[to] = v
+		}
+	}
+
+	// MySQL-style ssl-mode (VERIFY_IDENTITY) and MariaDB's boolean switches.
+	switch {
+	case raw["ssl-mode"] != "":
+		cfg["ssl-mode"] = strings.ToLower(strings.ReplaceAll(raw["ssl-mode"], "_", "-"))
+	case isOptionTrue(raw["ssl-verify-server-cert"]):
+		cfg["ssl-mode"] = sslModeVerifyIdentity
+	case raw["ssl"] != "" && !isOptionTrue(raw["ssl"]):
+		cfg["ssl-mode"] = sslModeDisabled
+	case isOptionTrue(raw["ssl"]):
+		cfg["ssl-mode"] = sslModeRequired
+	}
+	return cfg
+}
+
+func isOptionTrue(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "on", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// mergeConnectionConfig layers config.ini over the option files: any key
+// set in the tool's own section wins. Host and port fall back to the
+// mariadb client defaults.
+func mergeConnectionConfig(optionCfg, toolCfg map[string]string) map[string]string {
+	merged := make(map[string]string, len(optionCfg)+len(toolCfg))
+	for k, v := range optionCfg {
+		merged[k] = v
+	}
+	for k, v := range toolCfg {
+		merged[k] = v
+	}
+	// A TCP host in config.ini must not be overridden by a my.cnf socket.
+	if toolCfg["hostname"] != "" && toolCfg["socket"] == "" {
+		delete(merged, "socket")
+	}
+	if merged["socket"] == "" && merged["hostname"] == "" {
+		merged["hostname"] = "localhost"
+	}
+	if merged["socket"] == "" && merged["port"] == "" {
+		merged["port"] = "3306"
+	}
+	return merged
+}
@@ -0,0 +1,143 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseOptionValue(t *testing.T) {
+	cases := map[string]string{
+		`secret`:               "secret",
+		`  secret   # comment`: "secret",
+		`"p#ss word"`:          "p#ss word",
+		`'single q'`:           "single q",
+		`"a\"b\\c\sd"`:         `a"b\c d`,
+		`"x" # trailing`:       "x",
+		``:                     "",
+	}
+	for in, want := range cases {
+		got, err := parseOptionValue(in)
+		if err != nil || got != want {
+			t.Fatalf("parseOptionValue(%q)=%q err=%v, want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{`"unterminated`, `"x" y`} {
+		if _, err := parseOptionValue(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadOptionFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "my.cnf")
+	writeFile(t, main, `
+[mysqld]
+user=mysql
+port=3307
+
+[client]
+user = root
+password = "from main"
+host=db1
+!include extra.cnf
+!includedir conf.d
+`)
+	writeFile(t, filepath.Join(dir, "extra.cnf"), "[client-server]\nsocket=/run/mysqld/mysqld.sock\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "10-a.cnf"), "[client]\npassword=first\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "20-b.cnf"), "[client-mariadb]\npassword='second'\nssl_verify_server_cert\nssl-ca=/etc/ca.pem\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "ignored.txt"), "[client]\npassword=nope\n")
+
+	cfg, err := loadOptionFiles(Options{DefaultsFile: main})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"username": "root",
+		"password": "second",
+		"hostname": "db1",
+		"socket":   "/run/mysqld/mysqld.sock",
+		"ssl-ca":   "/etc/ca.pem",
+		"ssl-mode": sslModeVerifyIdentity,
+	}
+	for k, v := range want {
+		if cfg[k] != v {
+			t.Fatalf("%s=%q, want %q (cfg=%v)", k, cfg[k], v, cfg)
+		}
+	}
+	if _, ok := cfg["port"]; ok {
+		t.Fatalf("port from [mysqld] must be ignored: %v", cfg)
+	}
+
+	if _, err := loadOptionFiles(Options{DefaultsFile: filepath.Join(dir, "missing.cnf")}); err == nil {
+		t.Fatal("expected error for missing -defaults-file")
+	}
+
+	writeFile(t, filepath.Join(dir, "broken.cnf"), "[client]\n!include nope.cnf\n")
+	if _, err := loadOptionFiles(Options{DefaultsFile: filepath.Join(dir, "broken.cnf")}); err == nil {
+		t.Fatal("expected error for missing include")
+	}
+
+	// A bare password means "prompt", never the literal "1".
+	prompt := filepath.Join(dir, "prompt.cnf")
+	writeFile(t, prompt, "[client]\nuser=root\npassword=old\npassword\nsocket\nskip-ssl\n")
+	cfg, err = loadOptionFiles(Options{DefaultsFile: prompt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg["password"]; ok {
+		t.Fatalf("bare password must not set a password: %v", cfg)
+	}
+	if _, ok := cfg["socket"]; ok {
+		t.Fatalf("bare socket must be ignored: %v", cfg)
+	}
+	if cfg["username"] != "root" || cfg["ssl-mode"] != sslModeDisabled {
+		t.Fatalf("cfg=%v", cfg)
+	}
+
+	cfg, err = loadOptionFiles(Options{NoDefaults: true, DefaultsFile: main})
+	if err != nil || len(cfg) != 0 {
+		t.Fatalf("-no-defaults: cfg=%v err=%v", cfg, err)
+	}
+}
+
+func TestMergeConnectionConfig(t *testing.T) {
+	optionCfg := map[string]string{"username": "root", "password": "a", "socket": "/run/mysqld/mysqld.sock"}
+
+	merged := mergeConnectionConfig(optionCfg, map[string]string{"password": "b", "hostname": "db2", "port": "3306"})
+	if merged["password"] != "b" || merged["username"] != "root" {
+		t.Fatalf("config.ini must override option files: %v", merged)
+	}
+	if merged["socket"] != "" {
+		t.Fatalf("hostname in config.ini must drop my.cnf socket: %v", merged)
+	}
+
+	merged = mergeConnectionConfig(map[string]string{"username": "root"}, nil)
+	if merged["hostname"] != "localhost" || merged["port"] != "3306" {
+		t.Fatalf("expected client defaults: %v", merged)
+	}
+}
@@ -0,0 +1,337 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+
+	// outputMarkdown is only accepted by security-report.
+	outputMarkdown = "markdown"
+)
+
+// Exit codes. Skipped items are not failures: the tool is idempotent.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitPartial  = 3 // batch completed, but one or more lines failed
+	exitFindings = 4 // audit or security-report found something to look at
+
+	exitInterrupted = 130 // stopped by SIGINT/SIGTERM before all lines ran
+)
+
+// batchExitCode maps a finished batch to its exit code. An interrupt
+// wins over failures, as the lines not run were never tried.
+func batchExitCode(s BatchSummary) int {
+	switch {
+	case s.NotRun > 0:
+		return exitInterrupted
+	case s.Failed > 0:
+		return exitPartial
+	}
+	return exitOK
+}
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputJSONL:
+		return nil
+	}
+	return fmt.Errorf("invalid output format '%s' (allowed: text, json, jsonl)", format)
+}
+
+func (s CreateStatus) String() string {
+	switch s {
+	case StatusSkipped:
+		return "skipped"
+	case StatusDryRun:
+		return "dry_run"
+	case StatusCreated:
+		return "created"
+	case StatusDeleted:
+		return "deleted"
+	case StatusRotated:
+		return "rotated"
+	case StatusAdopted:
+		return "adopted"
+	default:
+		return "unknown"
+	}
+}
+
+// ResultRecord is the machine-readable form of one processed item.
+// Field names are part of the public output schema; do not rename them.
+type ResultRecord struct {
+	Line          int      `json:"line,omitempty"`
+	Input         string   `json:"input"`
+	Action        string   `json:"action"`
+	Status        string   `json:"status"`
+	RequestedName string   `json:"requested_name"`
+	Name          string   `json:"name"`
+	Username      string   `json:"username"`
+	Host          string   `json:"host"`
+	Hosts         []string `json:"hosts,omitempty"`
+	Profile       string   `json:"profile"`
+	Auth          string   `json:"auth,omitempty"`
+	Password      string   `json:"password"`
+	ReadOnlyUser  string   `json:"readonly_username"`
+	ReadOnlyPass  string   `json:"readonly_password"`
+	Message       string   `json:"message"`
+	CSVExported   bool     `json:"csv_exported"`
+	Error         string   `json:"error"`
+	PreviousRun   bool     `json:"previous_run,omitempty"`
+}
+
+type BatchSummary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+	DryRun  int `json:"dry_run"`
+	Deleted int `json:"deleted"`
+	Rotated int `json:"rotated"`
+	Adopted int `json:"adopted"`
+	Failed  int `json:"failed"`
+	NotRun  int `json:"not_run,omitempty"`
+
+	// Lines completed by an earlier run and skipped with -resume.
+	Previous          int `json:"previous,omitempty"`
+	CreatedPreviously int `json:"created_previously,omitempty"`
+}
+
+type BatchReport struct {
+	Action  string         `json:"action"`
+	File    string         `json:"file"`
+	Results []ResultRecord `json:"results"`
+	Summary BatchSummary   `json:"summary"`
+}
+
+func newResultRecord(action string, line int, input string, res *CreateResult, err error) ResultRecord {
+	rec := ResultRecord{Line: line, Input: input, Action: action}
+	if err != nil {
+		rec.Status = "error"
+		rec.Error = err.Error()
+		return rec
+	}
+	rec.Status = res.Status.String()
+	rec.RequestedName = res.RequestedName
+	rec.Name = res.Name
+	rec.Username = res.Username
+	rec.Host = res.UserHost
+	if len(res.UserHosts) > 1 {
+		rec.Hosts = res.UserHosts
+	}
+	rec.Profile = res.Profile
+	rec.Auth = res.Auth
+	rec.ReadOnlyUser = res.ReadOnlyUsername
+	if res.Status == StatusCreated || res.Status == StatusRotated {
+		rec.Password = res.Password
+		rec.ReadOnlyPass = res.ReadOnlyPassword
+	}
+	rec.Message = res.Message
+	rec.CSVExported = res.CSVExported
+	return rec
+}
+
+// statusNotRun marks batch lines skipped because the run was interrupted.
+const statusNotRun = "not_run"
+
+func notRunRecord(action string, line int, input string) ResultRecord {
+	return ResultRecord{Line: line, Input: input, Action: action, Status: statusNotRun}
+}
+
+func previousRecord(action string, line int, input, status string) ResultRecord {
+	return ResultRecord{Line: line, Input: input, Action: action, Status: status, PreviousRun: true,
+		Message: fmt.Sprintf("%s in an earlier run (-resume)", status)}
+}
+
+func (s *BatchSummary) addPrevious(status string) {
+	s.Total++
+	s.Previous++
+	if status == StatusCreated.String() {
+		s.CreatedPreviously++
+	}
+}
+
+// summaryLine is the closing line of a text batch run.
+func summaryLine(s BatchSummary) string {
+	var parts []string
+	for _, c := range []struct {
+		n     int
+		label string
+	}{
+		{s.Created, "created"},
+		{s.CreatedPreviously, "created previously"},
+		{s.Previous - s.CreatedPreviously, "done previously"},
+		{s.Skipped, "skipped"},
+		{s.DryRun, "dry run"},
+		{s.Deleted, "deleted"},
+		{s.Rotated, "rotated"},
+		{s.Adopted, "adopted"},
+		{s.Failed, "failed"},
+		{s.NotRun, "not run"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	return fmt.Sprintf("Summary: %d lines: %s", s.Total, strings.Join(parts, ", "))
+}
+
+func (s *BatchSummary) add(res *CreateResult, err error) {
+	s.Total++
+	if err != nil {
+		s.Failed++
+		return
+	}
+	switch res.Status {
+	case StatusCreated:
+		s.Created++
+	case StatusSkipped:
+		s.Skipped++
+	case StatusDryRun:
+		s.DryRun++
+	case StatusDeleted:
+		s.Deleted++
+	case StatusRotated:
+		s.Rotated++
+	case StatusAdopted:
+		s.Adopted++
+	}
+}
+
+/* ===============================
+   Reporters
+================================= */
+
+// reporter receives the outcome of every item, in input order. Lines not
+// started because of an interrupt go to notRun, lines completed by an
+// earlier run (-resume) to previous.
+type reporter interface {
+	report(line int, input string, res *CreateResult, err error)
+	notRun(line int, input string)
+	previous(line int, input, status string)
+	finish() error
+	summary() BatchSummary
+}
+
+func newReporter(opts Options, file string) reporter {
+	switch opts.Output {
+	case outputJSON:
+		return &jsonReporter{w: os.Stdout, doc: BatchReport{Action: opts.Command, File: file, Results: []ResultRecord{}}}
+	case outputJSONL:
+		return &jsonlReporter{enc: json.NewEncoder(os.Stdout), action: opts.Command}
+	default:
+		return &textReporter{opts: opts}
+	}
+}
+
+type textReporter struct {
+	opts Options
+	sum  BatchSummary
+}
+
+func (r *textReporter) report(line int, input string, res *CreateResult, err error) {
+	r.sum.add(res, err)
+	if err != nil {
+		fmt.Println("❌", fmt.Sprintf("Line %d (%s): %v", line, input, err))
+		return
+	}
+	printResult(r.opts, res)
+}
+
+func (r *textReporter) notRun(int, string) {
+	r.sum.Total++
+	r.sum.NotRun++
+}
+
+func (r *textReporter) previous(_ int, _ string, status string) {
+	r.sum.addPrevious(status)
+}
+
+func (r *textReporter) finish() error {
+	if r.sum.Total > 0 {
+		fmt.Println(summaryLine(r.sum))
+	}
+	return nil
+}
+
+func (r *textReporter) summary() BatchSummary { return r.sum }
+
+type jsonlReporter struct {
+	enc    *json.Encoder
+	action string
+	sum    BatchSummary
+}
+
+func (r *jsonlReporter) report(line int, input string, res *CreateResult, err error) {
+	r.sum.add(res, err)
+	_ = r.enc.Encode(newResultRecord(r.action, line, input, res, err))
+}
+
+func (r *jsonlReporter) notRun(line int, input string) {
+	r.sum.Total++
+	r.sum.NotRun++
+	_ = r.enc.Encode(notRunRecord(r.action, line, input))
+}
+
+func (r *jsonlReporter) previous(line int, input, status string) {
+	r.sum.addPrevious(status)
+	_ = r.enc.Encode(previousRecord(r.action, line, input, status))
+}
+
+func (r *jsonlReporter) finish() error         { return nil }
+func (r *jsonlReporter) summary() BatchSummary { return r.sum }
+
+type jsonReporter struct {
+	w   io.Writer
+	doc BatchReport
+}
+
+func (r *jsonReporter) report(line int, input string, res *CreateResult, err error) {
+	r.doc.Summary.add(res, err)
+	r.doc.Results = append(r.doc.Results, newResultRecord(r.doc.Action, line, input, res, err))
+}
+
+func (r *jsonReporter) notRun(line int, input string) {
+	r.doc.Summary.Total++
+	r.doc.Summary.NotRun++
+	r.doc.Results = append(r.doc.Results, notRunRecord(r.doc.Action, line, input))
+}
+
+func (r *jsonReporter) previous(line int, input, status string) {
+	r.doc.Summary.addPrevious(status)
+	r.doc.Results = append(r.doc.Results, previousRecord(r.doc.Action, line, input, status))
+}
+
+func (r *jsonReporter) finish() error {
+	return writeJSON(r.w, r.doc)
+}
+
+func (r *jsonReporter) summary() BatchSummary { return r.doc.Summary }
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,165 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testResult(status CreateStatus) *CreateResult {
+	return &CreateResult{
+		Status: status, RequestedName: "My-Shop", Name: "my_shop", Username: "my_shop",
+		UserHost: "localhost", Profile: "app", Password: "secret",
+		ReadOnlyUsername: "my_shop_ro", ReadOnlyPassword: "rosecret",
+	}
+}
+
+func TestNewResultRecord(t *testing.T) {
+	cases := []struct {
+		status       CreateStatus
+		wantStatus   string
+		wantPassword bool
+	}{
+		{StatusCreated, "created", true},
+		{StatusRotated, "rotated", true},
+		{StatusSkipped, "skipped", false},
+		{StatusDryRun, "dry_run", false},
+		{StatusDeleted, "deleted", false},
+		{StatusAdopted, "adopted", false},
+	}
+	for _, c := range cases {
+		rec := newResultRecord(cmdCreate, 3, "My-Shop", testResult(c.status), nil)
+		if rec.Status != c.wantStatus || rec.Line != 3 || rec.Name != "my_shop" || rec.Error != "" {
+			t.Fatalf("%s: %+v", c.wantStatus, rec)
+		}
+		if (rec.Password == "secret") != c.wantPassword || (rec.ReadOnlyPass == "rosecret") != c.wantPassword {
+			t.Fatalf("%s: password=%q readonly_password=%q", c.wantStatus, rec.Password, rec.ReadOnlyPass)
+		}
+	}
+
+	rec := newResultRecord(cmdDelete, 0, "shop", nil, errors.New("boom"))
+	if rec.Status != "error" || rec.Error != "boom" || rec.Password != "" || rec.Action != cmdDelete {
+		t.Fatalf("error record: %+v", rec)
+	}
+
+	multi := testResult(StatusCreated)
+	multi.UserHosts = []string{"localhost", "10.0.0.5"}
+	if rec := newResultRecord(cmdCreate, 0, "x", multi, nil); len(rec.Hosts) != 2 {
+		t.Fatalf("hosts: %+v", rec.Hosts)
+	}
+	multi.UserHosts = []string{"localhost"}
+	if rec := newResultRecord(cmdCreate, 0, "x", multi, nil); rec.Hosts != nil {
+		t.Fatalf("single host should omit hosts: %+v", rec.Hosts)
+	}
+}
+
+func TestBatchSummaryAdd(t *testing.T) {
+	var s BatchSummary
+	for _, st := range []CreateStatus{StatusCreated, StatusCreated, StatusSkipped, StatusDryRun,
+		StatusDeleted, StatusRotated, StatusAdopted} {
+		s.add(testResult(st), nil)
+	}
+	s.add(nil, errors.New("boom"))
+	s.addPrevious("created")
+	s.addPrevious("skipped")
+
+	want := BatchSummary{Total: 10, Created: 2, Skipped: 1, DryRun: 1, Deleted: 1, Rotated: 1,
+		Adopted: 1, Failed: 1, Previous: 2, CreatedPreviously: 1}
+	if s != want {
+		t.Fatalf("summary = %+v, want %+v", s, want)
+	}
+}
+
+func TestBatchExitCode(t *testing.T) {
+	cases := []struct {
+		sum  BatchSummary
+		want int
+	}{
+		{BatchSummary{Total: 2, Created: 1, Skipped: 1}, exitOK},
+		{BatchSummary{Total: 2, Created: 1, Failed: 1}, exitPartial},
+		{BatchSummary{Total: 2, Created: 1, NotRun: 1}, exitInterrupted},
+		{BatchSummary{Total: 3, Failed: 1, NotRun: 2}, exitInterrupted},
+		{BatchSummary{}, exitOK},
+	}
+	for _, c := range cases {
+		if got := batchExitCode(c.sum); got != c.want {
+			t.Fatalf("%+v: exit %d, want %d", c.sum, got, c.want)
+		}
+	}
+	if exitOK != 0 || exitError != 1 || exitUsage != 2 || exitPartial != 3 {
+		t.Fatalf("documented exit codes changed")
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &jsonReporter{w: &buf, doc: BatchReport{Action: cmdCreate, File: "list.txt", Results: []ResultRecord{}}}
+	r.report(1, "shop", testResult(StatusCreated), nil)
+	r.report(2, "bad name", nil, errors.New("invalid name"))
+	r.notRun(3, "later")
+	if err := r.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc BatchReport
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("not one JSON document: %v\n%s", err, buf.String())
+	}
+	if doc.Action != cmdCreate || doc.File != "list.txt" || len(doc.Results) != 3 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	if doc.Results[0].Password != "secret" || doc.Results[1].Status != "error" || doc.Results[2].Status != statusNotRun {
+		t.Fatalf("results = %+v", doc.Results)
+	}
+	if doc.Summary != (BatchSummary{Total: 3, Created: 1, Failed: 1, NotRun: 1}) || r.summary() != doc.Summary {
+		t.Fatalf("summary = %+v", doc.Summary)
+	}
+}
+
+func TestJSONLReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &jsonlReporter{enc: json.NewEncoder(&buf), action: cmdRotate}
+	r.report(1, "shop", testResult(StatusRotated), nil)
+	r.report(2, "other", testResult(StatusSkipped), nil)
+	r.previous(3, "done", "rotated")
+	if err := r.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want one line per item, got %d:\n%s", len(lines), buf.String())
+	}
+	var recs []ResultRecord
+	for _, l := range lines {
+		var rec ResultRecord
+		if err := json.Unmarshal([]byte(l), &rec); err != nil {
+			t.Fatalf("line %q: %v", l, err)
+		}
+		if rec.Action != cmdRotate {
+			t.Fatalf("action = %q", rec.Action)
+		}
+		recs = append(recs, rec)
+	}
+	if recs[0].Password == "" || recs[1].Password != "" || !recs[2].PreviousRun {
+		t.Fatalf("records = %+v", recs)
+	}
+	if want := (BatchSummary{Total: 3, Rotated: 1, Skipped: 1, Previous: 1}); r.summary() != want {
+		t.Fatalf("summary = %+v, want %+v", r.summary(), want)
+	}
+}
@@ -15,23 +15,241 @@ package main
 
 import (
 	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#%&"
 
 func generatePassword(n int) (string, error) {
+	return randomString(passwordAlphabet, n)
+}
+
+// randomString draws n characters uniformly from alphabet.
+func randomString(alphabet string, n int) (string, error) {
 	var sb strings.Builder
 	sb.Grow(n)
 
-	max := big.NewInt(int64(len(passwordAlphabet)))
+	max := big.NewInt(int64(len(alphabet)))
 	for i := 0; i < n; i++ {
 		r, err := rand.Int(rand.Reader, max)
 		if err != nil {
 			return "", err
 		}
-		sb.WriteByte(passwordAlphabet[r.Int64()])
+		sb.WriteByte(alphabet[r.Int64()])
 	}
 	return sb.String(), nil
 }
+
+/* ===============================
+   Password policy
+================================= */
+
+const (
+	passwordModeRandom   = "random"
+	passwordModeDiceware = "diceware"
+
+	passwordSection = "password"
+
+	// Rejection sampling gives up after this many draws. With length >= 8
+	// and at most four required classes that is never reached in practice.
+	maxPasswordAttempts = 1000
+)
+
+// passwordClasses are the character classes a policy can draw from; the
+// symbols are the historical ones.
+var passwordClasses = map[string]string{
+	"lower":  "abcdefghijklmnopqrstuvwxyz",
+	"upper":  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digit":  "0123456789",
+	"symbol": "!#%&",
+}
+
+var passwordClassOrder = []string{"lower", "upper", "digit", "symbol"}
+
+//go:embed wordlist.txt
+var wordlistData string
+
+// wordlist has 2048 words, 11 bits each.
+var wordlist = strings.Fields(wordlistData)
+
+// PasswordPolicy is the [password] section of config.ini. The zero value
+// generates what the tool always did: 20 characters from passwordAlphabet.
+type PasswordPolicy struct {
+	Mode      string
+	Length    int
+	Classes   []string
+	Exclude   string
+	Require   []string
+	Words     int
+	Separator string
+
+	alphabet string
+	required []string // alphabets of the required classes
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// parsePasswordPolicy validates a [password] section. Missing keys keep
+// the defaults.
+func parsePasswordPolicy(sec map[string]string) (PasswordPolicy, error) {
+	p := PasswordPolicy{
+		Mode:      passwordModeRandom,
+		Length:    20,
+		Classes:   passwordClassOrder,
+		Words:     6,
+		Separator: "-",
+	}
+	intKey := func(key string, dst *int, min, max int) error {
+		v := strings.TrimSpace(sec[key])
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("password %s must be a number from %d to %d", key, min, max)
+		}
+		*dst = n
+		return nil
+	}
+
+	if v := strings.ToLower(strings.TrimSpace(sec["mode"])); v != "" {
+		p.Mode = v
+	}
+	if err := intKey("length", &p.Length, 8, 256); err != nil {
+		return p, err
+	}
+	if err := intKey("words", &p.Words, 4, 20); err != nil {
+		return p, err
+	}
+	if v, ok := sec["classes"]; ok {
+		p.Classes = splitList(v)
+	}
+	p.Exclude = sec["exclude"]
+	p.Require = splitList(sec["require"])
+	if v, ok := sec["separator"]; ok {
+		p.Separator = strings.TrimSpace(v)
+	}
+
+	switch p.Mode {
+	case passwordModeDiceware:
+		if len(p.Require) > 0 {
+			return p, errors.New("password require only applies to mode = random")
+		}
+		if len(p.Separator) > 1 || strings.IndexFunc(p.Separator, func(r rune) bool {
+			return r > unicode.MaxASCII || unicode.IsLetter(r) || !unicode.IsPrint(r)
+		}) >= 0 {
+			return p, fmt.Errorf("password separator '%s' must be one non-letter ASCII character", p.Separator)
+		}
+		return p, nil
+	case passwordModeRandom:
+	default:
+		return p, fmt.Errorf("unknown password mode '%s' (expected random or diceware)", p.Mode)
+	}
+
+	// The alphabet is the chosen classes minus excluded characters.
+	chosen := make(map[string]string)
+	var sb strings.Builder
+	for _, c := range p.Classes {
+		chars, ok := passwordClasses[c]
+		if !ok {
+			return p, fmt.Errorf("unknown password class '%s' (expected lower, upper, digit, symbol)", c)
+		}
+		if _, dup := chosen[c]; dup {
+			continue
+		}
+		chars = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(p.Exclude, r) {
+				return -1
+			}
+			return r
+		}, chars)
+		chosen[c] = chars
+		sb.WriteString(chars)
+	}
+	p.alphabet = sb.String()
+	if len(p.alphabet) < 10 {
+		return p, errors.New("password alphabet has fewer than 10 characters after exclude")
+	}
+
+	for _, c := range p.Require {
+		chars, ok := chosen[c]
+		switch {
+		case !ok:
+			return p, fmt.Errorf("password require '%s' is not one of the classes", c)
+		case chars == "":
+			return p, fmt.Errorf("password require '%s' has no characters left after exclude", c)
+		}
+		p.required = append(p.required, chars)
+	}
+	return p, nil
+}
+
+// loadPasswordPolicy reads [password] from config.ini, if present.
+func loadPasswordPolicy(configPath string) (PasswordPolicy, error) {
+	sections, err := profileSections(configPath)
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	return parsePasswordPolicy(sections[passwordSection])
+}
+
+// generate returns a password following the policy. Required classes are
+// met by drawing whole passwords until one has them all: every accepted
+// password is as likely as any other, unlike forcing one character per
+// class into fixed or shuffled positions.
+func (p PasswordPolicy) generate() (string, error) {
+	if p.Mode == "" {
+		return generatePassword(20)
+	}
+	if p.Mode == passwordModeDiceware {
+		return passphrase(p.Words, p.Separator)
+	}
+
+	for i := 0; i < maxPasswordAttempts; i++ {
+		pw, err := randomString(p.alphabet, p.Length)
+		if err != nil {
+			return "", err
+		}
+		if p.satisfies(pw) {
+			return pw, nil
+		}
+	}
+	return "", errors.New("could not generate a password meeting the policy; relax require or raise length")
+}
+
+func (p PasswordPolicy) satisfies(pw string) bool {
+	for _, chars := range p.required {
+		if !strings.ContainsAny(pw, chars) {
+			return false
+		}
+	}
+	return true
+}
+
+// passphrase joins n words drawn uniformly from the embedded wordlist.
+func passphrase(n int, sep string) (string, error) {
+	max := big.NewInt(int64(len(wordlist)))
+	words := make([]string, n)
+	for i := range words {
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		words[i] = wordlist[r.Int64()]
+	}
+	return strings.Join(words, sep), nil
+}
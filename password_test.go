@@ -13,7 +13,10 @@
 
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestGeneratePassword(t *testing.T) {
 	p1, err := generatePassword(20)
@@ -32,3 +35,104 @@ func TestGeneratePassword(t *testing.T) {
 		t.Fatalf("passwords equal; expected randomness")
 	}
 }
+
+func TestParsePasswordPolicy(t *testing.T) {
+	p, err := parsePasswordPolicy(nil)
+	if err != nil {
+		t.Fatalf("default: %v", err)
+	}
+	if p.Mode != passwordModeRandom || p.Length != 20 || p.alphabet != passwordAlphabet {
+		t.Fatalf("default policy = %+v", p)
+	}
+
+	p, err = parsePasswordPolicy(map[string]string{
+		"length": "32", "classes": "lower, Upper,digit", "exclude": "lIO01", "require": "upper,digit",
+	})
+	if err != nil {
+		t.Fatalf("custom: %v", err)
+	}
+	if p.Length != 32 || len(p.alphabet) != 26+26+10-5 || len(p.required) != 2 {
+		t.Fatalf("custom policy = %+v", p)
+	}
+
+	bad := []map[string]string{
+		{"mode": "pronounceable"},
+		{"length": "7"},
+		{"length": "abc"},
+		{"classes": "lower,emoji"},
+		{"classes": "digit", "exclude": "0"},
+		{"require": "symbol", "classes": "lower,upper"},
+		{"require": "symbol", "exclude": "!#%&"},
+		{"mode": "diceware", "require": "upper"},
+		{"mode": "diceware", "words": "3"},
+		{"mode": "diceware", "separator": "x"},
+		{"mode": "diceware", "separator": "--"},
+	}
+	for _, sec := range bad {
+		if _, err := parsePasswordPolicy(sec); err == nil {
+			t.Errorf("%v: expected error", sec)
+		}
+	}
+}
+
+func TestPasswordPolicyGenerate(t *testing.T) {
+	p, err := parsePasswordPolicy(map[string]string{
+		"length": "8", "exclude": "#%&", "require": "lower,upper,digit,symbol",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		pw, err := p.generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pw) != 8 || strings.ContainsAny(pw, "#%&") {
+			t.Fatalf("password %q breaks length or exclude", pw)
+		}
+		for _, class := range []string{"lower", "upper", "digit"} {
+			if !strings.ContainsAny(pw, passwordClasses[class]) {
+				t.Fatalf("password %q lacks %s", pw, class)
+			}
+		}
+		if !strings.Contains(pw, "!") {
+			t.Fatalf("password %q lacks symbol", pw)
+		}
+	}
+
+	var zero PasswordPolicy
+	if pw, err := zero.generate(); err != nil || len(pw) != 20 {
+		t.Fatalf("zero policy: %q, %v", pw, err)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	if len(wordlist) != 2048 {
+		t.Fatalf("wordlist has %d words, want 2048", len(wordlist))
+	}
+	seen := make(map[string]bool)
+	for _, w := range wordlist {
+		if seen[w] {
+			t.Fatalf("duplicate word %q", w)
+		}
+		seen[w] = true
+	}
+
+	p, err := parsePasswordPolicy(map[string]string{"mode": "diceware", "words": "7", "separator": "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw, err := p.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Split(pw, ".")
+	if len(words) != 7 {
+		t.Fatalf("passphrase %q: %d words", pw, len(words))
+	}
+	for _, w := range words {
+		if !seen[w] {
+			t.Fatalf("passphrase word %q not in wordlist", w)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ===============================
+   Plan / apply
+================================= */
+
+const planVersion = 1
+
+const redactedPassword = "<redacted>"
+
+const (
+	planCreate  = "create"
+	planSkip    = "skip"
+	planInvalid = "invalid"
+)
+
+// Plan is the reviewable output of "plan" and the input of "apply". It
+// records the statements that will run (passwords redacted) and the
+// existence checks they rely on.
+type Plan struct {
+	Version      int        `json:"version"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Server       string     `json:"server"`
+	Address      string     `json:"address"`
+	UserHost     string     `json:"user_host"`
+	Profile      string     `json:"profile"`
+	Privileges   []string   `json:"privileges"`
+	Auth         string     `json:"auth,omitempty"`
+	ReadOnlyUser bool       `json:"readonly_user"`
+	Items        []PlanItem `json:"items"`
+}
+
+type PlanItem struct {
+	Line           int      `json:"line,omitempty"`
+	Input          string   `json:"input"`
+	Name           string   `json:"name"`
+	Charset        string   `json:"charset,omitempty"`
+	Collation      string   `json:"collation,omitempty"`
+	Action         string   `json:"action"`
+	DatabaseExists bool     `json:"database_exists"`
+	ExistingUsers  []string `json:"existing_users"`
+	Statements     []string `json:"statements"`
+	Message        string   `json:"message,omitempty"`
+}
+
+// serverAddress identifies the server a plan was made for.
+func serverAddress(cfg map[string]string) string {
+	if cfg["socket"] != "" {
+		return "unix:" + cfg["socket"]
+	}
+	return cfg["hostname"] + ":" + cfg["port"]
+}
+
+// plannedStatements renders what processDatabase runs for name, with
+// passwords redacted.
+func plannedStatements(opts Options, name string) []string {
+	stmts := []string{createDatabaseSQL(name, opts.Charset, opts.Collation)}
+	for _, a := range createAccounts(opts, name) {
+		for _, h := range userHosts(opts.UserHost) {
+			stmts = append(stmts,
+				createUserSQL(a.User, h, opts.Auth, redactedPassword),
+				a.grantSQL(name, h))
+		}
+	}
+	return stmts
+}
+
+func accountUsers(opts Options, name string) []string {
+	var users []string
+	for _, a := range createAccounts(opts, name) {
+		users = append(users, a.User)
+	}
+	return users
+}
+
+// planItem validates one entry and records the server state it finds.
+// opts already carries the entry's charset and collation.
+func planItem(ctx context.Context, db *sql.DB, opts Options, e batchEntry) (PlanItem, error) {
+	item := PlanItem{Line: e.Line, Input: e.Input, Charset: opts.Charset, Collation: opts.Collation,
+		ExistingUsers: []string{}, Statements: []string{}}
+
+	hosts := userHosts(opts.UserHost)
+	_, name, err := resolveName(opts, e.name())
+	if err == nil {
+		err = validateUserHosts(name, hosts, opts.AllowWildcardHost)
+	}
+	if err == nil && opts.ReadOnlyUser {
+		err = validateUserHosts(readOnlyUserName(name), hosts, opts.AllowWildcardHost)
+	}
+	if err != nil {
+		item.Action = planInvalid
+		item.Message = err.Error()
+		return item, nil
+	}
+	item.Name = name
+
+	reason, err := checkCharsetCollation(ctx, db, opts.Charset, opts.Collation)
+	if err == nil && reason == "" {
+		reason, err = checkAuthPlugins(ctx, db, opts.Auth)
+	}
+	if err != nil {
+		return item, err
+	}
+	if reason != "" {
+		item.Action = planInvalid
+		item.Message = reason
+		return item, nil
+	}
+
+	dbExists, existing, err := dbOrUserExists(ctx, db, name, hosts, accountUsers(opts, name)...)
+	if err != nil {
+		return item, err
+	}
+	item.DatabaseExists = dbExists
+	if existing != nil {
+		item.ExistingUsers = existing
+	}
+
+	if dbExists || len(existing) > 0 {
+		item.Action = planSkip
+		item.Message = "database or user already exists"
+		return item, nil
+	}
+
+	item.Action = planCreate
+	item.Statements = plannedStatements(opts, name)
+	return item, nil
+}
+
+func buildPlan(db *sql.DB, opts Options, entries []batchEntry, server, address string) (*Plan, error) {
+	opts = withCreateDefaults(opts)
+
+	p := &Plan{
+		Version:      planVersion,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		Server:       server,
+		Address:      address,
+		UserHost:     opts.UserHost,
+		Profile:      opts.Privileges.Name,
+		Privileges:   opts.Privileges.Privileges,
+		Auth:         planAuth(opts.Auth),
+		ReadOnlyUser: opts.ReadOnlyUser,
+		Items:        []PlanItem{},
+	}
+
+	for _, e := range entries {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		item, err := planItem(ctx, db, e.options(opts), e)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("line %d (%s): %w", e.Line, e.Input, err)
+		}
+		p.Items = append(p.Items, item)
+	}
+	return p, nil
+}
+
+// runPlan writes a plan for -f <file> or a single name.
+func runPlan(db *sql.DB, opts Options, server, address string) (*Plan, error) {
+	// A plan describes a create run; -disambiguate applies as it would there.
+	opts.Command = cmdCreate
+
+	var entries []batchEntry
+	if opts.FileList != "" {
+		var err error
+		entries, err = readBatchFile(opts.FileList)
+		if err != nil {
+			return nil, err
+		}
+		entries, err = checkBatchCollisions(opts, entries)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		entries = []batchEntry{{Input: strings.TrimSpace(opts.Target)}}
+	}
+
+	p, err := buildPlan(db, opts, entries, server, address)
+	if err != nil {
+		return nil, err
+	}
+
+	out := os.Stdout
+	if opts.PlanOut != "" {
+		f, err := os.OpenFile(opts.PlanOut, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeJSON(out, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plan) counts() (create, skip, invalid int) {
+	for _, it := range p.Items {
+		switch it.Action {
+		case planCreate:
+			create++
+		case planSkip:
+			skip++
+		case planInvalid:
+			invalid++
+		}
+	}
+	return create, skip, invalid
+}
+
+func readPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	if p.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", p.Version, planVersion)
+	}
+	return &p, nil
+}
+
+// planOptions turns the settings recorded in the plan back into Options.
+// Everything is validated again: the plan file may have been edited.
+func planOptions(opts Options, p *Plan) (Options, error) {
+	privs, err := parsePrivileges(strings.Join(p.Privileges, ","))
+	if err != nil {
+		return opts, fmt.Errorf("plan privileges: %w", err)
+	}
+	auth, err := parseAuth(p.Auth)
+	if err != nil {
+		return opts, fmt.Errorf("plan auth: %w", err)
+	}
+	opts.UserHost = p.UserHost
+	opts.Privileges = PrivilegeProfile{Name: p.Profile, Privileges: privs}
+	opts.Auth = auth
+	opts.ReadOnlyUser = p.ReadOnlyUser
+	// Charset and collation are recorded per item.
+	opts.Charset, opts.Collation = "", ""
+	// Plan names are final identifiers.
+	opts.Normalize = false
+	return opts, nil
+}
+
+// planAuth is the auth recorded in a plan; empty means the default.
+func planAuth(a AuthSpec) string {
+	if a.isDefault() {
+		return ""
+	}
+	return a.String()
+}
+
+// verifyPlan checks that the plan is internally consistent and that the
+// server still looks exactly like it did at plan time. It returns every
+// problem found.
+func verifyPlan(db *sql.DB, opts Options, p *Plan, server, address string) []string {
+	var problems []string
+
+	if p.Server != server || p.Address != address {
+		problems = append(problems, fmt.Sprintf("plan is for server %s (%s), not %s (%s)",
+			p.Server, p.Address, server, address))
+		return problems
+	}
+
+	seen := make(map[string]int)
+	for _, it := range p.Items {
+		where := fmt.Sprintf("line %d (%s)", it.Line, it.Input)
+
+		switch it.Action {
+		case planCreate, planSkip:
+		case planInvalid:
+			problems = append(problems, fmt.Sprintf("%s: invalid in plan: %s", where, it.Message))
+			continue
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown action '%s'", where, it.Action))
+			continue
+		}
+
+		if first, dup := seen[it.Name]; dup {
+			problems = append(problems, fmt.Sprintf("%s: '%s' already planned on line %d", where, it.Name, first))
+			continue
+		}
+		seen[it.Name] = it.Line
+
+		if err := validateUserHosts(it.Name, userHosts(opts.UserHost), opts.AllowWildcardHost); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			continue
+		}
+		if err := validatePlanCharset(it); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			continue
+		}
+		itOpts := it.entry().options(opts)
+		if it.Action == planCreate && !slices.Equal(it.Statements, plannedStatements(itOpts, it.Name)) {
+			problems = append(problems, fmt.Sprintf("%s: statements differ from what would run (edited plan?)", where))
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		dbExists, existing, err := dbOrUserExists(ctx, db, it.Name, userHosts(opts.UserHost), accountUsers(opts, it.Name)...)
+		cancel()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			continue
+		}
+		if dbExists != it.DatabaseExists || !slices.Equal(nonNil(existing), nonNil(it.ExistingUsers)) {
+			problems = append(problems, fmt.Sprintf(
+				"%s: server changed since plan (database exists: %t -> %t, users: %v -> %v)",
+				where, it.DatabaseExists, dbExists, it.ExistingUsers, nonNil(existing)))
+		}
+	}
+	return problems
+}
+
+// validatePlanCharset re-checks values that are spliced into DDL.
+func validatePlanCharset(it PlanItem) error {
+	if it.Charset != "" {
+		if err := validateCharset(it.Charset); err != nil {
+			return err
+		}
+	}
+	if it.Collation != "" {
+		return validateCollation(it.Collation)
+	}
+	return nil
+}
+
+func (it PlanItem) entry() batchEntry {
+	return batchEntry{Line: it.Line, Input: it.Input, Name: it.Name, Charset: it.Charset, Collation: it.Collation}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// runApply executes a reviewed plan. Nothing runs unless verifyPlan finds
+// no problems at all.
+func runApply(ctx context.Context, db *sql.DB, opts Options, path, server, address string, rep reporter) error {
+	p, err := readPlan(path)
+	if err != nil {
+		return err
+	}
+
+	applyOpts, err := planOptions(opts, p)
+	if err != nil {
+		return err
+	}
+
+	if problems := verifyPlan(db, applyOpts, p, server, address); len(problems) > 0 {
+		return errors.New("plan verification failed, nothing was changed:\n  " +
+			strings.Join(problems, "\n  "))
+	}
+
+	entries := make([]batchEntry, len(p.Items))
+	skipped := make(map[string]string)
+	for i, it := range p.Items {
+		entries[i] = it.entry()
+		if it.Action == planSkip {
+			skipped[it.Name] = it.Message
+		}
+	}
+
+	fn := func(ctx context.Context, db *sql.DB, itOpts Options, name string) (*CreateResult, error) {
+		if msg, ok := skipped[name]; ok {
+			return &CreateResult{
+				Status:   StatusSkipped,
+				Name:     name,
+				Username: name,
+				UserHost: applyOpts.UserHost,
+				Message:  fmt.Sprintf("Skipping '%s': %s (per plan)", name, msg),
+			}, nil
+		}
+		return processDatabase(ctx, db, itOpts, name)
+	}
+
+	runBatch(ctx, db, applyOpts, entries, fn, rep)
+	return rep.finish()
+}
@@ -0,0 +1,124 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlannedStatementsRedacted(t *testing.T) {
+	opts := withCreateDefaults(Options{ReadOnlyUser: true})
+	stmts := plannedStatements(opts, "shop")
+
+	if len(stmts) != 5 {
+		t.Fatalf("expected 5 statements, got %d: %q", len(stmts), stmts)
+	}
+	if stmts[0] != createDatabaseSQL("shop", "", "") {
+		t.Fatalf("first statement should create the database, got %q", stmts[0])
+	}
+	for _, s := range stmts {
+		if strings.HasPrefix(s, "CREATE USER") && !strings.Contains(s, "'"+redactedPassword+"'") {
+			t.Fatalf("password not redacted: %q", s)
+		}
+	}
+	if !strings.Contains(stmts[3], "'shop_ro'@'localhost'") {
+		t.Fatalf("expected read-only user, got %q", stmts[3])
+	}
+}
+
+func TestPlannedStatementsMultipleHosts(t *testing.T) {
+	opts := withCreateDefaults(Options{UserHost: "localhost, 10.0.0.5"})
+	stmts := plannedStatements(opts, "shop")
+
+	if len(stmts) != 5 {
+		t.Fatalf("expected 5 statements, got %d: %q", len(stmts), stmts)
+	}
+	for i, host := range []string{"localhost", "10.0.0.5"} {
+		if !strings.HasPrefix(stmts[1+2*i], "CREATE USER 'shop'@'"+host+"'") ||
+			!strings.Contains(stmts[2+2*i], "'shop'@'"+host+"'") {
+			t.Fatalf("expected create and grant for %s, got %q", host, stmts)
+		}
+	}
+}
+
+func TestPlannedStatementsAuth(t *testing.T) {
+	opts := withCreateDefaults(Options{})
+	opts.Auth, _ = parseAuth("unix_socket,ed25519")
+	stmts := plannedStatements(opts, "shop")
+
+	want := "CREATE USER 'shop'@'localhost' IDENTIFIED VIA unix_socket OR ed25519 USING PASSWORD('" + redactedPassword + "')"
+	if stmts[1] != want {
+		t.Fatalf("got %q, want %q", stmts[1], want)
+	}
+}
+
+func TestReadPlanVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.json")
+
+	writeFile(t, path, `{"version": 99, "items": []}`)
+	if _, err := readPlan(path); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+
+	writeFile(t, path, `{"version": 1, "server": "mariadb", "items": []}`)
+	p, err := readPlan(path)
+	if err != nil || p.Server != "mariadb" {
+		t.Fatalf("readPlan: %+v, %v", p, err)
+	}
+}
+
+func TestPlanOptionsRevalidatesPrivileges(t *testing.T) {
+	p := &Plan{UserHost: "localhost", Profile: "x", Privileges: []string{"SELECT", "SUPER"}}
+	if _, err := planOptions(Options{}, p); err == nil {
+		t.Fatal("expected SUPER in an edited plan to be rejected")
+	}
+
+	p.Privileges = []string{"SELECT"}
+	opts, err := planOptions(Options{Normalize: true}, p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if opts.Normalize || opts.Privileges.Privileges[0] != "SELECT" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+// These cases are all rejected before the server is queried.
+func TestVerifyPlanOffline(t *testing.T) {
+	opts := withCreateDefaults(Options{})
+
+	p := &Plan{Server: "mariadb", Address: "db1:3306"}
+	if problems := verifyPlan(nil, opts, p, "mariadb", "db2:3306"); len(problems) != 1 {
+		t.Fatalf("expected server mismatch, got %q", problems)
+	}
+
+	p.Items = []PlanItem{
+		{Line: 1, Input: "bad name", Action: planInvalid, Message: "invalid"},
+		{Line: 2, Input: "shop", Name: "shop", Action: planCreate, Statements: []string{"DROP DATABASE mysql"}},
+		{Line: 3, Input: "shop", Name: "shop", Action: planSkip},
+		{Line: 4, Input: "x", Name: "x", Action: "drop"},
+	}
+	problems := verifyPlan(nil, opts, p, "mariadb", "db1:3306")
+	if len(problems) != 4 {
+		t.Fatalf("expected 4 problems, got %d: %q", len(problems), problems)
+	}
+	for i, want := range []string{"invalid in plan", "statements differ", "already planned on line 2", "unknown action"} {
+		if !strings.Contains(problems[i], want) {
+			t.Fatalf("problem %d: expected %q, got %q", i, want, problems[i])
+		}
+	}
+}
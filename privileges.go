@@ -0,0 +1,221 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const defaultPrivilegeProfile = "owner"
+
+// profileSectionPrefix marks config.ini sections that define privilege
+// profiles, e.g. [profile:reporting].
+const profileSectionPrefix = "profile:"
+
+const allPrivileges = "ALL PRIVILEGES"
+
+// schemaPrivileges is the whitelist of database-level privileges a profile
+// may grant. GRANT OPTION is deliberately absent: created users must never
+// be able to hand out rights themselves.
+var schemaPrivileges = map[string]bool{
+	allPrivileges:             true,
+	"ALTER":                   true,
+	"ALTER ROUTINE":           true,
+	"CREATE":                  true,
+	"CREATE ROUTINE":          true,
+	"CREATE TEMPORARY TABLES": true,
+	"CREATE VIEW":             true,
+	"DELETE":                  true,
+	"DELETE HISTORY":          true,
+	"DROP":                    true,
+	"EVENT":                   true,
+	"EXECUTE":                 true,
+	"INDEX":                   true,
+	"INSERT":                  true,
+	"LOCK TABLES":             true,
+	"REFERENCES":              true,
+	"SELECT":                  true,
+	"SHOW CREATE ROUTINE":     true,
+	"SHOW VIEW":               true,
+	"TRIGGER":                 true,
+	"UPDATE":                  true,
+}
+
+var builtinPrivilegeProfiles = map[string]string{
+	"owner":     allPrivileges,
+	"readwrite": "SELECT, INSERT, UPDATE, DELETE, CREATE TEMPORARY TABLES, LOCK TABLES, EXECUTE, SHOW VIEW",
+	"readonly":  "SELECT, SHOW VIEW",
+	"migrator": "SELECT, INSERT, UPDATE, DELETE, CREATE, ALTER, DROP, INDEX, REFERENCES, " +
+		"CREATE VIEW, SHOW VIEW, TRIGGER, CREATE ROUTINE, ALTER ROUTINE, EXECUTE, " +
+		"CREATE TEMPORARY TABLES, LOCK TABLES",
+}
+
+type PrivilegeProfile struct {
+	Name       string
+	Privileges []string
+	Auth       AuthSpec // from auth= in [profile:<name>]; empty for built-ins
+}
+
+// grantList renders the privileges for a GRANT statement.
+func (p PrivilegeProfile) grantList() string {
+	return strings.Join(p.Privileges, ", ")
+}
+
+// parsePrivileges splits a comma-separated privilege list, canonicalizes
+// each entry and checks it against schemaPrivileges.
+func parsePrivileges(list string) ([]string, error) {
+	var privs []string
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(list, ",") {
+		p := strings.ToUpper(strings.Join(strings.Fields(part), " "))
+		if p == "" {
+			continue
+		}
+		if p == "ALL" {
+			p = allPrivileges
+		}
+		if !schemaPrivileges[p] {
+			return nil, fmt.Errorf("privilege '%s' not allowed", strings.TrimSpace(part))
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		privs = append(privs, p)
+	}
+
+	if len(privs) == 0 {
+		return nil, errors.New("empty privilege list")
+	}
+	if seen[allPrivileges] && len(privs) > 1 {
+		return nil, errors.New("ALL PRIVILEGES cannot be combined with other privileges")
+	}
+	return privs, nil
+}
+
+func loadPrivilegeProfile(configPath, name string) (PrivilegeProfile, error) {
+	sections, err := profileSections(configPath)
+	if err != nil {
+		return PrivilegeProfile{}, err
+	}
+	return resolvePrivilegeProfile(sections, name)
+}
+
+// profileSections reads config.ini for [profile:<name>] sections. The file
+// is optional when option files provide the credentials.
+func profileSections(configPath string) (map[string]map[string]string, error) {
+	sections, err := parseINI(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]map[string]string{}, nil
+	}
+	return sections, err
+}
+
+// resolvePrivilegeProfile looks the profile up in the parsed config first,
+// so [profile:<name>] sections can override the built-in ones.
+func resolvePrivilegeProfile(sections map[string]map[string]string, name string) (PrivilegeProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = defaultPrivilegeProfile
+	}
+
+	list, ok := builtinPrivilegeProfiles[name]
+	auth := ""
+	if sec, found := sections[profileSectionPrefix+name]; found {
+		list, ok = sec["privileges"], true
+		auth = sec["auth"]
+	}
+	if !ok {
+		return PrivilegeProfile{}, fmt.Errorf("unknown privilege profile '%s' (available: %s)",
+			name, strings.Join(privilegeProfileNames(sections), ", "))
+	}
+
+	privs, err := parsePrivileges(list)
+	if err != nil {
+		return PrivilegeProfile{}, fmt.Errorf("privilege profile '%s': %w", name, err)
+	}
+	a, err := parseAuth(auth)
+	if err != nil {
+		return PrivilegeProfile{}, fmt.Errorf("privilege profile '%s': %w", name, err)
+	}
+	return PrivilegeProfile{Name: name, Privileges: privs, Auth: a}, nil
+}
+
+func privilegeProfileNames(sections map[string]map[string]string) []string {
+	seen := make(map[string]bool)
+	for name := range builtinPrivilegeProfiles {
+		seen[name] = true
+	}
+	for sec := range sections {
+		if strings.HasPrefix(sec, profileSectionPrefix) {
+			seen[strings.TrimPrefix(sec, profileSectionPrefix)] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// allPrivilegesExpanded is what a database-level ALL PRIVILEGES shows up as
+// in information_schema.SCHEMA_PRIVILEGES on every supported server.
+// Newer servers add DELETE HISTORY and SHOW CREATE ROUTINE; they are not
+// required.
+var allPrivilegesExpanded = []string{
+	"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "REFERENCES",
+	"INDEX", "ALTER", "CREATE TEMPORARY TABLES", "LOCK TABLES", "EXECUTE",
+	"CREATE VIEW", "SHOW VIEW", "CREATE ROUTINE", "ALTER ROUTINE", "EVENT",
+	"TRIGGER",
+}
+
+// diffPrivileges compares the privileges a profile wants with the ones a
+// user actually holds on a database. A profile with ALL PRIVILEGES never
+// has extras; if anything is missing it is granted as a whole.
+func diffPrivileges(want, have []string) (missing, extra []string) {
+	held := make(map[string]bool, len(have))
+	for _, p := range have {
+		held[p] = true
+	}
+
+	if len(want) == 1 && want[0] == allPrivileges {
+		for _, p := range allPrivilegesExpanded {
+			if !held[p] {
+				return []string{allPrivileges}, nil
+			}
+		}
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool, len(want))
+	for _, p := range want {
+		wanted[p] = true
+		if !held[p] {
+			missing = append(missing, p)
+		}
+	}
+	for _, p := range have {
+		if !wanted[p] {
+			extra = append(extra, p)
+		}
+	}
+	sort.Strings(extra)
+	return missing, extra
+}
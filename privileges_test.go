@@ -0,0 +1,94 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import "testing"
+
+func TestParsePrivileges(t *testing.T) {
+	got, err := parsePrivileges(" select, show   view ,SELECT ")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(got) != 2 || got[0] != "SELECT" || got[1] != "SHOW VIEW" {
+		t.Fatalf("unexpected privileges: %q", got)
+	}
+
+	if got, err := parsePrivileges("all"); err != nil || got[0] != allPrivileges {
+		t.Fatalf("expected ALL -> ALL PRIVILEGES, got %q err=%v", got, err)
+	}
+
+	bad := []string{"", " , ", "GRANT OPTION", "SUPER", "SELECT; DROP", "ALL, SELECT"}
+	for _, s := range bad {
+		if _, err := parsePrivileges(s); err == nil {
+			t.Fatalf("expected error for %q, got nil", s)
+		}
+	}
+}
+
+func TestResolvePrivilegeProfile(t *testing.T) {
+	sections := map[string]map[string]string{
+		"profile:readonly":  {"privileges": "SELECT"},
+		"profile:reporting": {"privileges": "SELECT, SHOW VIEW"},
+		"profile:broken":    {"privileges": "SUPER"},
+		"profile:ops":       {"privileges": "SELECT", "auth": "unix_socket OR ed25519"},
+		"profile:badauth":   {"privileges": "SELECT", "auth": "pam"},
+	}
+
+	p, err := resolvePrivilegeProfile(sections, "")
+	if err != nil || p.Name != "owner" || p.grantList() != allPrivileges {
+		t.Fatalf("default profile: %+v err=%v", p, err)
+	}
+
+	// config overrides built-in
+	p, err = resolvePrivilegeProfile(sections, "ReadOnly")
+	if err != nil || p.grantList() != "SELECT" {
+		t.Fatalf("override: %+v err=%v", p, err)
+	}
+
+	if _, err := resolvePrivilegeProfile(sections, "reporting"); err != nil {
+		t.Fatalf("custom profile: %v", err)
+	}
+	if _, err := resolvePrivilegeProfile(sections, "broken"); err == nil {
+		t.Fatal("expected error for non-whitelisted privilege")
+	}
+	if _, err := resolvePrivilegeProfile(sections, "nope"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+
+	p, err = resolvePrivilegeProfile(sections, "ops")
+	if err != nil || p.Auth.String() != "unix_socket OR ed25519" {
+		t.Fatalf("profile auth: %+v err=%v", p, err)
+	}
+	if _, err := resolvePrivilegeProfile(sections, "badauth"); err == nil {
+		t.Fatal("expected error for unsupported auth plugin")
+	}
+}
+
+func TestDiffPrivileges(t *testing.T) {
+	missing, extra := diffPrivileges([]string{"SELECT", "SHOW VIEW"}, []string{"SELECT", "INSERT", "DELETE"})
+	if len(missing) != 1 || missing[0] != "SHOW VIEW" {
+		t.Fatalf("missing: %q", missing)
+	}
+	if len(extra) != 2 || extra[0] != "DELETE" || extra[1] != "INSERT" {
+		t.Fatalf("extra: %q", extra)
+	}
+
+	all := []string{allPrivileges}
+	if missing, extra := diffPrivileges(all, allPrivilegesExpanded); missing != nil || extra != nil {
+		t.Fatalf("expanded ALL should match: %q %q", missing, extra)
+	}
+	if missing, _ := diffPrivileges(all, []string{"SELECT"}); len(missing) != 1 || missing[0] != allPrivileges {
+		t.Fatalf("expected ALL PRIVILEGES to be granted, got %q", missing)
+	}
+}
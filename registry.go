@@ -0,0 +1,484 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+/* ===============================
+   Registry
+================================= */
+
+// The registry lives on the server so every operator and machine sees the
+// same record of what this tool created.
+const (
+	registrySchema = "mariadb_tool"
+	registryTable  = "accounts"
+)
+
+const (
+	roleOwner    = "owner"
+	roleReadOnly = "readonly"
+	roleExtra    = "extra"
+)
+
+// RegistryEntry is one account the tool created (or adopted).
+type RegistryEntry struct {
+	Database    string    `json:"database"`
+	User        string    `json:"user"`
+	Host        string    `json:"host"`
+	Role        string    `json:"role"`
+	Profile     string    `json:"profile"`
+	Privileges  string    `json:"privileges"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	ToolVersion string    `json:"tool_version"`
+	Owner       string    `json:"owner,omitempty"`
+	Ticket      string    `json:"ticket,omitempty"`
+}
+
+func registryTableName() string {
+	return quoteIdent(registrySchema) + "." + quoteIdent(registryTable)
+}
+
+const registryDDL = `CREATE TABLE IF NOT EXISTS %s (
+	db_name      VARCHAR(64)  NOT NULL,
+	user_name    VARCHAR(80)  NOT NULL,
+	user_host    VARCHAR(255) NOT NULL,
+	role         VARCHAR(16)  NOT NULL,
+	profile      VARCHAR(64)  NOT NULL DEFAULT '',
+	privileges   TEXT         NOT NULL,
+	created_by   VARCHAR(255) NOT NULL DEFAULT '',
+	created_at   DATETIME     NOT NULL,
+	tool_version VARCHAR(32)  NOT NULL,
+	owner        VARCHAR(255) NOT NULL DEFAULT '',
+	ticket       VARCHAR(255) NOT NULL DEFAULT '',
+	PRIMARY KEY (db_name, user_name, user_host)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`
+
+var (
+	registryMu    sync.Mutex
+	registryReady bool
+)
+
+// ensureRegistry creates the admin schema and table on first use.
+func ensureRegistry(ctx context.Context, db *sql.DB) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registryReady {
+		return nil
+	}
+	if err := execSQL(ctx, db, "CREATE DATABASE IF NOT EXISTS "+quoteIdent(registrySchema)); err != nil {
+		return fmt.Errorf("create registry schema: %w", err)
+	}
+	if err := execSQL(ctx, db, fmt.Sprintf(registryDDL, registryTableName())); err != nil {
+		return fmt.Errorf("create registry table: %w", err)
+	}
+	registryReady = true
+	return nil
+}
+
+// registryExists is used by read paths, which must not create anything.
+func registryExists(ctx context.Context, db *sql.DB) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.TABLES
+		 WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, registrySchema, registryTable,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check registry: %w", err)
+	}
+	return n > 0, nil
+}
+
+// currentOSUser names whoever ran the tool, for the created_by column.
+func currentOSUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func newRegistryEntry(opts Options, name, user, host, role, profile, privileges string) RegistryEntry {
+	return RegistryEntry{
+		Database:    name,
+		User:        user,
+		Host:        host,
+		Role:        role,
+		Profile:     profile,
+		Privileges:  privileges,
+		CreatedBy:   currentOSUser(),
+		CreatedAt:   time.Now().Truncate(time.Second),
+		ToolVersion: toolVersion,
+		Owner:       opts.Owner,
+		Ticket:      opts.Ticket,
+	}
+}
+
+// recordAccounts stores entries in one transaction. A database that is
+// dropped by hand and created again replaces its old rows.
+func recordAccounts(ctx context.Context, db *sql.DB, entries []RegistryEntry) error {
+	if err := ensureRegistry(ctx, db); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := `INSERT INTO ` + registryTableName() + `
+		(db_name, user_name, user_host, role, profile, privileges, created_by, created_at, tool_version, owner, ticket)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		role = VALUES(role), profile = VALUES(profile), privileges = VALUES(privileges),
+		created_by = VALUES(created_by), created_at = VALUES(created_at),
+		tool_version = VALUES(tool_version), owner = VALUES(owner), ticket = VALUES(ticket)`
+	for _, e := range entries {
+		if _, err := tx.ExecContext(ctx, q,
+			e.Database, e.User, e.Host, e.Role, e.Profile, e.Privileges,
+			e.CreatedBy, e.CreatedAt, e.ToolVersion, e.Owner, e.Ticket); err != nil {
+			return fmt.Errorf("record %s in registry: %w", quoteUserHost(e.User, e.Host), err)
+		}
+	}
+	return tx.Commit()
+}
+
+func isRegistered(ctx context.Context, db *sql.DB, name, user, host string) (bool, error) {
+	ok, err := registryExists(ctx, db)
+	if err != nil || !ok {
+		return false, err
+	}
+	var one int
+	err = db.QueryRowContext(ctx,
+		`SELECT 1 FROM `+registryTableName()+`
+		 WHERE db_name = ? AND user_name = ? AND user_host = ?`, name, user, host,
+	).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read registry: %w", err)
+	}
+	return true, nil
+}
+
+// registryEntries returns the accounts registered for database name, or
+// all of them when name is empty.
+func registryEntries(ctx context.Context, db *sql.DB, name string) ([]RegistryEntry, error) {
+	ok, err := registryExists(ctx, db)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	q := `SELECT db_name, user_name, user_host, role, profile, privileges,
+		created_by, created_at, tool_version, owner, ticket
+		FROM ` + registryTableName()
+	var args []any
+	if name != "" {
+		q += " WHERE db_name = ?"
+		args = append(args, name)
+	}
+	q += " ORDER BY db_name, role <> 'owner', user_name, user_host"
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read registry: %w", err)
+	}
+	defer rows.Close()
+
+	var out []RegistryEntry
+	for rows.Next() {
+		var e RegistryEntry
+		if err := rows.Scan(&e.Database, &e.User, &e.Host, &e.Role, &e.Profile, &e.Privileges,
+			&e.CreatedBy, &e.CreatedAt, &e.ToolVersion, &e.Owner, &e.Ticket); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// updateRegisteredPrivileges keeps the registry in line after sync
+// changed the grants of an account.
+func updateRegisteredPrivileges(ctx context.Context, db *sql.DB, name string, u desiredUser) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE `+registryTableName()+` SET profile = ?, privileges = ?
+		 WHERE db_name = ? AND user_name = ? AND user_host = ?`,
+		u.Profile.Name, u.Profile.grantList(), name, u.Name, u.Host)
+	if err != nil {
+		return fmt.Errorf("update registry: %w", err)
+	}
+	return nil
+}
+
+func unregisterAccount(ctx context.Context, db *sql.DB, name, user, host string) error {
+	_, err := db.ExecContext(ctx,
+		`DELETE FROM `+registryTableName()+` WHERE db_name = ? AND user_name = ? AND user_host = ?`,
+		name, user, host)
+	return err
+}
+
+/* ===============================
+   Adopt
+================================= */
+
+// matchProfile names the built-in profile whose privileges equal have.
+func matchProfile(have []string) string {
+	names := make([]string, 0, len(builtinPrivilegeProfiles))
+	for n := range builtinPrivilegeProfiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p, err := resolvePrivilegeProfile(nil, n)
+		if err != nil {
+			continue
+		}
+		if missing, extra := diffPrivileges(p.Privileges, have); len(missing) == 0 && len(extra) == 0 {
+			return n
+		}
+	}
+	return ""
+}
+
+// adoptDatabase registers a pair created by hand or before the registry
+// existed. Only pairs whose grants look exactly like ours qualify.
+func adoptDatabase(ctx context.Context, db *sql.DB, opts Options, inputName string) (*CreateResult, error) {
+	if opts.UserHost == "" {
+		opts.UserHost = "localhost"
+	}
+
+	requested, name, err := resolveName(opts, inputName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUserHost(name, opts.UserHost, opts.AllowWildcardHost); err != nil {
+		return nil, err
+	}
+
+	res := &CreateResult{
+		Status:        StatusUnknown,
+		RequestedName: requested,
+		Name:          name,
+		Username:      name,
+		UserHost:      opts.UserHost,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	dbExists, existing, err := dbOrUserExists(ctx, db, name, []string{opts.UserHost})
+	if err != nil {
+		return nil, err
+	}
+	if !dbExists || len(existing) == 0 {
+		res.Status = StatusSkipped
+		res.Message = fmt.Sprintf("Skipping '%s': database and user %s must both exist",
+			name, quoteUserHost(name, opts.UserHost))
+		return res, nil
+	}
+
+	registered, err := isRegistered(ctx, db, name, name, opts.UserHost)
+	if err != nil {
+		return nil, err
+	}
+	if registered {
+		res.Status = StatusSkipped
+		res.Message = fmt.Sprintf("Skipping '%s': already registered", name)
+		return res, nil
+	}
+
+	// A <name>_ro account may belong to someone else (even a database
+	// of its own), so it is only claimed as the companion when asked to.
+	users := []string{name}
+	if opts.ReadOnlyUser {
+		ro := readOnlyUserName(name)
+		exists, err := userExists(ctx, db, ro, opts.UserHost)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			res.Status = StatusSkipped
+			res.Message = fmt.Sprintf("Skipping '%s': companion %s missing",
+				name, quoteUserHost(ro, opts.UserHost))
+			return res, nil
+		}
+		users = append(users, ro)
+	}
+
+	var entries []RegistryEntry
+	for _, u := range users {
+		reason, err := checkGrantsManaged(ctx, db, u, opts.UserHost, name)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			res.Status = StatusSkipped
+			res.Message = fmt.Sprintf("Skipping '%s': %s (cannot adopt)", name, reason)
+			return res, nil
+		}
+		have, err := schemaGrants(ctx, db, u, opts.UserHost, name)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(have)
+
+		role := roleOwner
+		if u != name {
+			role = roleReadOnly
+			res.ReadOnlyUsername = u
+		} else {
+			res.Profile = matchProfile(have)
+		}
+		entries = append(entries, newRegistryEntry(opts, name, u, opts.UserHost, role,
+			matchProfile(have), strings.Join(have, ", ")))
+	}
+
+	accounts := make([]string, len(entries))
+	for i, e := range entries {
+		accounts[i] = e.User
+	}
+
+	if opts.DryRun {
+		res.Status = StatusDryRun
+		res.Message = fmt.Sprintf("Would register database '%s' with user %s",
+			name, quoteUsersHost(accounts, opts.UserHost))
+		return res, nil
+	}
+
+	ctx, cancelCommit, err := commitContext(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelCommit()
+
+	if err := recordAccounts(ctx, db, entries); err != nil {
+		return nil, err
+	}
+	res.Status = StatusAdopted
+	res.Message = fmt.Sprintf("Registered database '%s' with user %s",
+		name, quoteUsersHost(accounts, opts.UserHost))
+	return res, nil
+}
+
+/* ===============================
+   show
+================================= */
+
+func printRegistryList(w io.Writer, opts Options, entries []RegistryEntry) error {
+	if opts.Output != outputText {
+		if entries == nil {
+			entries = []RegistryEntry{}
+		}
+		return writeJSON(w, entries)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No registered databases.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tUSER\tHOST\tROLE\tPROFILE\tCREATED\tBY\tOWNER\tTICKET")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Database, e.User, e.Host, e.Role, dashIfEmpty(e.Profile),
+			e.CreatedAt.Format("2006-01-02 15:04"), dashIfEmpty(e.CreatedBy),
+			dashIfEmpty(e.Owner), dashIfEmpty(e.Ticket))
+	}
+	return tw.Flush()
+}
+
+// registryShow is one registered database with its live state.
+type registryShow struct {
+	Database string             `json:"database"`
+	Exists   bool               `json:"exists"`
+	Accounts []registryShowUser `json:"accounts"`
+}
+
+type registryShowUser struct {
+	RegistryEntry
+	Exists bool `json:"exists"`
+}
+
+func showRegistered(ctx context.Context, db *sql.DB, opts Options, inputName string) (*registryShow, error) {
+	_, name, err := resolveName(opts, inputName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := registryEntries(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("'%s' is not in the registry", name)
+	}
+
+	dbExists, err := databaseExists(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	show := &registryShow{Database: name, Exists: dbExists}
+	for _, e := range entries {
+		exists, err := userExists(ctx, db, e.User, e.Host)
+		if err != nil {
+			return nil, err
+		}
+		show.Accounts = append(show.Accounts, registryShowUser{RegistryEntry: e, Exists: exists})
+	}
+	return show, nil
+}
+
+func printRegistryShow(w io.Writer, opts Options, s *registryShow) error {
+	if opts.Output != outputText {
+		return writeJSON(w, s)
+	}
+
+	state := func(exists bool) string {
+		if exists {
+			return "exists"
+		}
+		return "MISSING"
+	}
+	fmt.Fprintf(w, "Database: %s (%s)\n", s.Database, state(s.Exists))
+	for _, a := range s.Accounts {
+		fmt.Fprintf(w, "\n  %s (%s, %s)\n", quoteUserHost(a.User, a.Host), a.Role, state(a.Exists))
+		fmt.Fprintf(w, "    Profile:    %s\n", dashIfEmpty(a.Profile))
+		fmt.Fprintf(w, "    Privileges: %s\n", a.Privileges)
+		fmt.Fprintf(w, "    Created:    %s by %s (mariadb-tool %s)\n",
+			a.CreatedAt.Format(time.RFC3339), dashIfEmpty(a.CreatedBy), a.ToolVersion)
+		if a.Owner != "" {
+			fmt.Fprintf(w, "    Owner:      %s\n", a.Owner)
+		}
+		if a.Ticket != "" {
+			fmt.Fprintf(w, "    Ticket:     %s\n", a.Ticket)
+		}
+	}
+	return nil
+}
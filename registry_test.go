@@ -0,0 +1,84 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchProfile(t *testing.T) {
+	if got := matchProfile([]string{"SHOW VIEW", "SELECT"}); got != "readonly" {
+		t.Fatalf("expected readonly, got %q", got)
+	}
+	if got := matchProfile(allPrivilegesExpanded); got != "owner" {
+		t.Fatalf("expected owner, got %q", got)
+	}
+	if got := matchProfile([]string{"SELECT", "DROP"}); got != "" {
+		t.Fatalf("expected no match, got %q", got)
+	}
+}
+
+func TestNewRegistryEntry(t *testing.T) {
+	opts := Options{Owner: "team-shop", Ticket: "OPS-42"}
+	e := newRegistryEntry(opts, "shop", "shop_ro", "localhost", roleReadOnly, "", "SELECT")
+	if e.Database != "shop" || e.User != "shop_ro" || e.Role != roleReadOnly || e.Privileges != "SELECT" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e.Owner != "team-shop" || e.Ticket != "OPS-42" || e.ToolVersion != toolVersion {
+		t.Fatalf("metadata not recorded: %+v", e)
+	}
+	if e.CreatedAt.IsZero() || e.CreatedAt.Nanosecond() != 0 {
+		t.Fatalf("created_at should be set to whole seconds, got %v", e.CreatedAt)
+	}
+}
+
+func TestResolveNameRejectsRegistrySchema(t *testing.T) {
+	if _, _, err := resolveName(Options{Normalize: true}, "Mariadb-Tool"); err == nil {
+		t.Fatal("expected the registry schema name to be reserved")
+	}
+}
+
+func TestPrintRegistryList(t *testing.T) {
+	entries := []RegistryEntry{{
+		Database: "shop", User: "shop", Host: "localhost", Role: roleOwner, Profile: "owner",
+		CreatedBy: "alice", CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), Ticket: "OPS-42",
+	}}
+
+	var buf bytes.Buffer
+	if err := printRegistryList(&buf, Options{Output: outputText}, entries); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "DATABASE") {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+	for _, want := range []string{"shop", "owner", "2026-03-01 12:30", "alice", "OPS-42"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("row misses %q: %s", want, lines[1])
+		}
+	}
+
+	buf.Reset()
+	if err := printRegistryList(&buf, Options{Output: outputJSON}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var got []RegistryEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("expected empty JSON array, got %q (%v)", buf.String(), err)
+	}
+}
@@ -0,0 +1,124 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// rotatePassword gives an existing tool-managed user a fresh password.
+// Unlike creation it never creates anything: a missing or hand-made user
+// is an error. With several hosts every entry gets the same password, in
+// one statement. Each account keeps its authentication plugins.
+func rotatePassword(ctx context.Context, db *sql.DB, opts Options, inputName string) (*CreateResult, error) {
+
+	if opts.UserHost == "" {
+		opts.UserHost = "localhost"
+	}
+
+	requested, name, err := resolveName(opts, inputName)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := userHosts(opts.UserHost)
+	if err := validateUserHosts(name, hosts, opts.AllowWildcardHost); err != nil {
+		return nil, err
+	}
+
+	res := &CreateResult{
+		Status:        StatusUnknown,
+		RequestedName: requested,
+		Name:          name,
+		Username:      name,
+		UserHost:      hosts[0],
+		UserHosts:     hosts,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	auths := make([]AuthSpec, len(hosts))
+	for i, h := range hosts {
+		exists, err := userExists(ctx, db, name, h)
+		if err != nil {
+			return nil, fmt.Errorf("check user exists: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("user %s does not exist", quoteUserHost(name, h))
+		}
+
+		reason, err := checkToolManaged(ctx, db, name, h, name)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, fmt.Errorf("refusing to rotate: %s (not tool-managed)", reason)
+		}
+
+		auths[i], err = accountAuth(ctx, db, name, h)
+		if err != nil {
+			return nil, err
+		}
+		if !auths[i].rotatable() {
+			return nil, fmt.Errorf("refusing to rotate: %s authenticates via %s (no password the tool can set)",
+				quoteUserHost(name, h), auths[i])
+		}
+	}
+
+	accounts := strings.Join(accountList([]string{name}, hosts), ", ")
+	if opts.DryRun {
+		res.Status = StatusDryRun
+		res.Message = fmt.Sprintf("Would rotate password for %s", accounts)
+		return res, nil
+	}
+
+	pw, err := opts.PasswordPolicy.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]string, len(hosts))
+	for i, h := range hosts {
+		specs[i] = quoteUserHost(name, h) + auths[i].identifiedSQL(pw)
+	}
+	alterSQL := "ALTER USER " + strings.Join(specs, ", ")
+
+	ctx, cancelCommit, err := commitContext(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelCommit()
+
+	if err := execSQL(ctx, db, alterSQL); err != nil {
+		return nil, fmt.Errorf("alter user %s: %w", accounts, err)
+	}
+
+	res.Password = pw
+	res.Status = StatusRotated
+
+	if opts.ExportCSV {
+		if err := saveToCSV(opts.CSVPath, name, name, pw); err != nil {
+			msg := fmt.Sprintf("WARNING: failed to export CSV for %s: %v", name, err)
+			logError(opts.ErrorLogPath, msg)
+		} else {
+			res.CSVExported = true
+		}
+	}
+
+	return res, nil
+}
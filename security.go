@@ -0,0 +1,325 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* ===============================
+   Security report
+================================= */
+
+const (
+	severityHigh   = "high"
+	severityMedium = "medium"
+	severityLow    = "low"
+)
+
+const (
+	checkEmptyPassword = "empty_password"
+	checkAnonymousUser = "anonymous_user"
+	checkOldPassword   = "old_password"
+	checkWildcardHost  = "wildcard_host"
+	checkAllPrivileges = "global_all_privileges"
+	checkGrantOption   = "grant_option"
+	checkTestDatabase  = "test_database"
+)
+
+type SecurityFinding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Subject  string `json:"subject"`
+	Detail   string `json:"detail"`
+}
+
+type SecuritySummary struct {
+	High   int `json:"high"`
+	Medium int `json:"medium"`
+	Low    int `json:"low"`
+}
+
+type SecurityReport struct {
+	Server      string            `json:"server"`
+	Address     string            `json:"address"`
+	Version     string            `json:"version"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Findings    []SecurityFinding `json:"findings"`
+	Summary     SecuritySummary   `json:"summary"`
+}
+
+// securityAccount is one row of mysql.user plus its SHOW GRANTS output.
+type securityAccount struct {
+	User       string
+	Host       string
+	Plugin     string
+	AuthString string
+	Password   string
+	Locked     bool
+	Grants     []string
+}
+
+type securityState struct {
+	Accounts []securityAccount
+	Schemas  []string
+	// DBGrants holds mysql.db rows as user, host and database pattern.
+	DBGrants [][3]string
+}
+
+// loadSecurityState reads mysql.user (MariaDB 10.4 or later), the schema
+// list and mysql.db. Roles are skipped.
+func loadSecurityState(ctx context.Context, db *sql.DB) (*securityState, error) {
+	s := &securityState{}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT User, Host, plugin, authentication_string, Password, account_locked
+		 FROM mysql.user WHERE is_role = 'N' ORDER BY User, Host`)
+	if err != nil {
+		return nil, fmt.Errorf("read mysql.user: %w", err)
+	}
+	for rows.Next() {
+		var a securityAccount
+		var locked string
+		if err := rows.Scan(&a.User, &a.Host, &a.Plugin, &a.AuthString, &a.Password, &locked); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Locked = locked == "Y"
+		s.Accounts = append(s.Accounts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range s.Accounts {
+		a := &s.Accounts[i]
+		if a.Grants, err = showGrants(ctx, db, a.User, a.Host); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err = db.QueryContext(ctx, "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA ORDER BY SCHEMA_NAME")
+	if err != nil {
+		return nil, fmt.Errorf("list databases: %w", err)
+	}
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.Schemas = append(s.Schemas, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, "SELECT User, Host, Db FROM mysql.db ORDER BY Db, User, Host")
+	if err != nil {
+		return nil, fmt.Errorf("read mysql.db: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var g [3]string
+		if err := rows.Scan(&g[0], &g[1], &g[2]); err != nil {
+			return nil, err
+		}
+		s.DBGrants = append(s.DBGrants, g)
+	}
+	return s, rows.Err()
+}
+
+// isTestDatabase matches the test databases (and the test\_% grant
+// pattern) that older installations ship with.
+func isTestDatabase(name string) bool {
+	return name == "test" || strings.HasPrefix(name, "test_") || strings.HasPrefix(name, `test\_`)
+}
+
+// passwordPlugins are the plugins for which an empty authentication
+// string means anyone can log in.
+var passwordPlugins = map[string]bool{
+	"":                      true,
+	"mysql_native_password": true,
+	"mysql_old_password":    true,
+	"ed25519":               true,
+}
+
+// grantLevel returns the "db.table" part of a SHOW GRANTS line.
+func grantLevel(g string) string {
+	i := strings.Index(g, " ON ")
+	j := strings.Index(g, " TO ")
+	if i < 0 || j < i {
+		return ""
+	}
+	return g[i+len(" ON ") : j]
+}
+
+func securityFindings(s *securityState) []SecurityFinding {
+	var findings []SecurityFinding
+	add := func(sev, check, subject, detail string) {
+		findings = append(findings, SecurityFinding{Severity: sev, Check: check, Subject: subject, Detail: detail})
+	}
+
+	for _, a := range s.Accounts {
+		acct := quoteUserHost(a.User, a.Host)
+
+		if a.User == "" {
+			add(severityHigh, checkAnonymousUser, acct, "anonymous account: any user name matches")
+		}
+		if !a.Locked && passwordPlugins[a.Plugin] && a.AuthString == "" && a.Password == "" {
+			add(severityHigh, checkEmptyPassword, acct, "account has no password")
+		}
+		if a.Plugin == "mysql_old_password" ||
+			(passwordPlugins[a.Plugin] && (len(a.AuthString) == 16 || len(a.Password) == 16)) {
+			add(severityHigh, checkOldPassword, acct, "pre-4.1 password hash (mysql_old_password)")
+		}
+
+		all := false
+		for _, g := range a.Grants {
+			if strings.HasPrefix(g, "GRANT ALL PRIVILEGES ON *.* ") {
+				all = true
+			}
+		}
+
+		if hasWildcardHost(a.Host) {
+			sev := severityMedium
+			if all {
+				sev = severityHigh
+			}
+			add(sev, checkWildcardHost, acct, fmt.Sprintf("host '%s' matches more than one client", a.Host))
+		}
+		if all {
+			add(severityMedium, checkAllPrivileges, acct, "ALL PRIVILEGES on *.*")
+		}
+		for _, g := range a.Grants {
+			if strings.Contains(g, " WITH GRANT OPTION") {
+				add(severityMedium, checkGrantOption, acct, "GRANT OPTION on "+grantLevel(g))
+			}
+		}
+	}
+
+	for _, name := range s.Schemas {
+		if isTestDatabase(name) {
+			add(severityLow, checkTestDatabase, name, "test database present")
+		}
+	}
+	for _, g := range s.DBGrants {
+		if isTestDatabase(g[2]) {
+			add(severityMedium, checkTestDatabase, g[2],
+				fmt.Sprintf("%s has privileges on test databases", quoteUserHost(g[0], g[1])))
+		}
+	}
+
+	rank := map[string]int{severityHigh: 0, severityMedium: 1, severityLow: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return rank[findings[i].Severity] < rank[findings[j].Severity]
+	})
+	return findings
+}
+
+func summarizeSecurity(findings []SecurityFinding) SecuritySummary {
+	var sum SecuritySummary
+	for _, f := range findings {
+		switch f.Severity {
+		case severityHigh:
+			sum.High++
+		case severityMedium:
+			sum.Medium++
+		case severityLow:
+			sum.Low++
+		}
+	}
+	return sum
+}
+
+func severityMarker(sev string) string {
+	switch sev {
+	case severityHigh:
+		return "❌"
+	case severityMedium:
+		return "⚠️ "
+	default:
+		return "ℹ️ "
+	}
+}
+
+// markdownCell escapes what would break a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func printSecurityReport(w io.Writer, opts Options, r SecurityReport) error {
+	switch opts.Output {
+	case outputJSON, outputJSONL:
+		return writeJSON(w, r)
+	case outputMarkdown:
+		fmt.Fprintf(w, "# Security report: %s\n\n", r.Server)
+		fmt.Fprintf(w, "Server `%s`, MariaDB %s, generated %s.\n\n",
+			r.Address, r.Version, r.GeneratedAt.Format(time.RFC3339))
+		if len(r.Findings) == 0 {
+			fmt.Fprintln(w, "No findings.")
+			return nil
+		}
+		fmt.Fprintln(w, "| Severity | Check | Subject | Detail |")
+		fmt.Fprintln(w, "|----------|-------|---------|--------|")
+		for _, f := range r.Findings {
+			fmt.Fprintf(w, "| %s | %s | `%s` | %s |\n",
+				f.Severity, f.Check, markdownCell(f.Subject), markdownCell(f.Detail))
+		}
+		fmt.Fprintf(w, "\n**%d findings:** %d high, %d medium, %d low\n",
+			len(r.Findings), r.Summary.High, r.Summary.Medium, r.Summary.Low)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Security report for %s (%s), MariaDB %s\n\n", r.Server, r.Address, r.Version)
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(w, "✅ No findings.")
+		return nil
+	}
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "%s %-6s  %-21s %-32s %s\n", severityMarker(f.Severity), f.Severity, f.Check, f.Subject, f.Detail)
+	}
+	fmt.Fprintf(w, "\n%d findings: %d high, %d medium, %d low\n",
+		len(r.Findings), r.Summary.High, r.Summary.Medium, r.Summary.Low)
+	return nil
+}
+
+func runSecurityReport(db *sql.DB, opts Options, server, address string) (SecurityReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	r := SecurityReport{Server: server, Address: address, GeneratedAt: time.Now().Truncate(time.Second)}
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&r.Version); err != nil {
+		return r, fmt.Errorf("read server version: %w", err)
+	}
+	s, err := loadSecurityState(ctx, db)
+	if err != nil {
+		return r, err
+	}
+	r.Findings = securityFindings(s)
+	if r.Findings == nil {
+		r.Findings = []SecurityFinding{}
+	}
+	r.Summary = summarizeSecurity(r.Findings)
+	return r, printSecurityReport(os.Stdout, opts, r)
+}
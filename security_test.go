@@ -0,0 +1,112 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityFindings(t *testing.T) {
+	s := &securityState{
+		Accounts: []securityAccount{
+			{User: "", Host: "localhost", Plugin: "mysql_native_password"},
+			{User: "mariadb.sys", Host: "localhost", Plugin: "mysql_native_password", Locked: true},
+			{User: "root", Host: "localhost", Plugin: "mysql_native_password", AuthString: "invalid",
+				Grants: []string{"GRANT ALL PRIVILEGES ON *.* TO `root`@`localhost` WITH GRANT OPTION"}},
+			{User: "admin", Host: "%", Plugin: "mysql_native_password", AuthString: "*0123456789ABCDEF0123456789ABCDEF01234567",
+				Grants: []string{"GRANT ALL PRIVILEGES ON *.* TO `admin`@`%`"}},
+			{User: "legacy", Host: "10.0.0.5", Plugin: "mysql_native_password", Password: "7e7a0a4d1b2c3d4e"},
+			{User: "app", Host: "localhost", Plugin: "unix_socket"},
+		},
+		Schemas:  []string{"information_schema", "shop", "test"},
+		DBGrants: [][3]string{{"", "%", `test\_%`}, {"shop", "localhost", "shop"}},
+	}
+	findings := securityFindings(s)
+
+	got := make(map[string]string)
+	for _, f := range findings {
+		got[f.Check+" "+f.Subject] = f.Severity
+	}
+	want := map[string]string{
+		"anonymous_user ''@'localhost'":            severityHigh,
+		"empty_password ''@'localhost'":            severityHigh,
+		"wildcard_host 'admin'@'%'":                severityHigh,
+		"global_all_privileges 'admin'@'%'":        severityMedium,
+		"global_all_privileges 'root'@'localhost'": severityMedium,
+		"grant_option 'root'@'localhost'":          severityMedium,
+		"old_password 'legacy'@'10.0.0.5'":         severityHigh,
+		"test_database test":                       severityLow,
+		`test_database test\_%`:                    severityMedium,
+	}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("%s: got severity %q, want %q", k, got[k], sev)
+		}
+	}
+	for _, k := range []string{
+		"empty_password 'mariadb.sys'@'localhost'", "empty_password 'app'@'localhost'",
+		"empty_password 'root'@'localhost'", "test_database shop",
+	} {
+		if _, ok := got[k]; ok {
+			t.Errorf("unexpected finding %s", k)
+		}
+	}
+	if len(findings) != len(want) {
+		t.Errorf("got %d findings, want %d: %v", len(findings), len(want), got)
+	}
+	for i := 1; i < len(findings); i++ {
+		if findings[i-1].Severity == severityLow && findings[i].Severity != severityLow {
+			t.Fatalf("findings not ordered by severity: %v", findings)
+		}
+	}
+}
+
+func TestGrantLevel(t *testing.T) {
+	if got := grantLevel("GRANT SELECT ON `shop`.* TO `shop`@`localhost` WITH GRANT OPTION"); got != "`shop`.*" {
+		t.Fatalf("got %q", got)
+	}
+	if got := grantLevel("GRANT PROXY"); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestPrintSecurityReportMarkdown(t *testing.T) {
+	r := SecurityReport{
+		Server:      "mariadb:prod",
+		Address:     "db1:3306",
+		Version:     "11.4.2-MariaDB",
+		GeneratedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Findings: []SecurityFinding{
+			{Severity: severityHigh, Check: checkEmptyPassword, Subject: "'a|b'@'%'", Detail: "account has no password"},
+		},
+		Summary: SecuritySummary{High: 1},
+	}
+	var buf bytes.Buffer
+	if err := printSecurityReport(&buf, Options{Output: outputMarkdown}, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Security report: mariadb:prod",
+		"| high | empty_password | `'a\\|b'@'%'` | account has no password |",
+		"**1 findings:** 1 high, 0 medium, 0 low",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
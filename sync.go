@@ -0,0 +1,794 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* ===============================
+   Manifest
+================================= */
+
+// Manifest is the desired state read by "sync". JSON manifests use the
+// same field names as YAML ones.
+type Manifest struct {
+	Defaults  ManifestDefaults   `yaml:"defaults" json:"defaults"`
+	Databases []ManifestDatabase `yaml:"databases" json:"databases"`
+}
+
+type ManifestDefaults struct {
+	Host    string `yaml:"host" json:"host"`
+	Profile string `yaml:"profile" json:"profile"`
+	Charset string `yaml:"charset" json:"charset"`
+	Auth    string `yaml:"auth" json:"auth"`
+}
+
+type ManifestDatabase struct {
+	Name    string         `yaml:"name" json:"name"`
+	Host    string         `yaml:"host" json:"host"`
+	Profile string         `yaml:"profile" json:"profile"`
+	Charset string         `yaml:"charset" json:"charset"`
+	Auth    string         `yaml:"auth" json:"auth"`
+	Users   []ManifestUser `yaml:"users" json:"users"`
+}
+
+// ManifestUser is an extra account with privileges on its database only.
+type ManifestUser struct {
+	Name    string `yaml:"name" json:"name"`
+	Host    string `yaml:"host" json:"host"`
+	Profile string `yaml:"profile" json:"profile"`
+	Auth    string `yaml:"auth" json:"auth"`
+}
+
+// loadManifest reads YAML, or JSON for *.json files. Unknown keys are an
+// error so typos do not silently fall back to defaults.
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&m)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+type desiredUser struct {
+	Name    string
+	Host    string
+	Profile PrivilegeProfile
+	Auth    AuthSpec
+}
+
+// manifestAuth picks the first auth set in the manifest (most specific
+// first), then the profile's, then -auth.
+func manifestAuth(opts Options, p PrivilegeProfile, values ...string) (AuthSpec, error) {
+	if v := firstNonEmpty(values...); v != "" {
+		return parseAuth(v)
+	}
+	if len(p.Auth.Plugins) > 0 {
+		return p.Auth, nil
+	}
+	return opts.Auth, nil
+}
+
+type desiredDatabase struct {
+	Name    string
+	Charset string
+	Owner   desiredUser
+	Users   []desiredUser
+}
+
+// resolveManifest applies defaults, normalization and validation. Database
+// names and accounts must be unique after normalization: an account shared
+// between databases would not be tool-managed.
+func resolveManifest(m *Manifest, opts Options, sections map[string]map[string]string) ([]desiredDatabase, error) {
+	profile := func(name string) (PrivilegeProfile, error) {
+		return resolvePrivilegeProfile(sections, name)
+	}
+
+	var out []desiredDatabase
+	dbSeen := make(map[string]string)
+	userSeen := make(map[string]string)
+
+	for i, md := range m.Databases {
+		where := fmt.Sprintf("databases[%d]", i)
+		if md.Name != "" {
+			where += " (" + md.Name + ")"
+		}
+
+		_, name, err := resolveName(opts, md.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+		if prev, dup := dbSeen[name]; dup {
+			return nil, fmt.Errorf("%s: '%s' already defined by %s", where, name, prev)
+		}
+		dbSeen[name] = where
+
+		host := canonicalHost(firstNonEmpty(md.Host, m.Defaults.Host, opts.UserHost, "localhost"))
+		charset := strings.ToLower(firstNonEmpty(md.Charset, m.Defaults.Charset))
+		if charset != "" {
+			if err := validateCharset(charset); err != nil {
+				return nil, fmt.Errorf("%s: %w", where, err)
+			}
+		}
+		owner, err := profile(firstNonEmpty(md.Profile, m.Defaults.Profile, defaultPrivilegeProfile))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+
+		ownerAuth, err := manifestAuth(opts, owner, md.Auth, m.Defaults.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+
+		d := desiredDatabase{
+			Name:    name,
+			Charset: charset,
+			Owner:   desiredUser{Name: name, Host: host, Profile: owner, Auth: ownerAuth},
+		}
+		for j, mu := range md.Users {
+			uwhere := fmt.Sprintf("%s.users[%d]", where, j)
+			if strings.TrimSpace(mu.Profile) == "" {
+				return nil, fmt.Errorf("%s: profile is required", uwhere)
+			}
+			p, err := profile(mu.Profile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", uwhere, err)
+			}
+			auth, err := manifestAuth(opts, p, mu.Auth, md.Auth, m.Defaults.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", uwhere, err)
+			}
+			d.Users = append(d.Users, desiredUser{
+				Name:    strings.TrimSpace(mu.Name),
+				Host:    canonicalHost(firstNonEmpty(mu.Host, host)),
+				Profile: p,
+				Auth:    auth,
+			})
+		}
+
+		for k, u := range append([]desiredUser{d.Owner}, d.Users...) {
+			uwhere := where
+			if k > 0 {
+				uwhere = fmt.Sprintf("%s.users[%d]", where, k-1)
+			}
+			if err := validateUserHost(u.Name, u.Host, opts.AllowWildcardHost); err != nil {
+				return nil, fmt.Errorf("%s: %w", uwhere, err)
+			}
+			account := quoteUserHost(u.Name, u.Host)
+			if prev, dup := userSeen[account]; dup {
+				return nil, fmt.Errorf("%s: account %s already defined by %s", uwhere, account, prev)
+			}
+			userSeen[account] = uwhere
+		}
+
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+/* ===============================
+   Diff
+================================= */
+
+const (
+	syncCreateDatabase = "create_database"
+	syncCreateUser     = "create_user"
+	syncGrant          = "grant"
+	syncRevoke         = "revoke"
+	syncAlterCharset   = "alter_charset"
+	syncDropDatabase   = "drop_database"
+	syncRefuse         = "refuse"
+)
+
+const (
+	syncApplied = "applied"
+	syncFailed  = "failed"
+	syncNotRun  = "not_run"
+)
+
+// SyncChange is one step towards the manifest. Refusals are listed too:
+// they are what sync will never touch because it is not tool-managed.
+type SyncChange struct {
+	Database string `json:"database"`
+	Action   string `json:"action"`
+	User     string `json:"user,omitempty"`
+	Detail   string `json:"detail"`
+	Status   string `json:"status,omitempty"`
+	Password string `json:"password,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	target desiredDatabase
+	user   desiredUser
+	stmt   string
+}
+
+// schemaGrants lists the database-level privileges user@host holds on name.
+func schemaGrants(ctx context.Context, db *sql.DB, user, host, name string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT PRIVILEGE_TYPE
+		 FROM information_schema.SCHEMA_PRIVILEGES
+		 WHERE GRANTEE = ? AND TABLE_SCHEMA = ?`, quoteUserHost(user, host), name)
+	if err != nil {
+		return nil, fmt.Errorf("read schema privileges: %w", err)
+	}
+	defer rows.Close()
+
+	var privs []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		privs = append(privs, p)
+	}
+	return privs, rows.Err()
+}
+
+func schemaCharset(ctx context.Context, db *sql.DB, name string) (string, error) {
+	var cs string
+	err := db.QueryRowContext(ctx,
+		`SELECT DEFAULT_CHARACTER_SET_NAME
+		 FROM information_schema.SCHEMATA
+		 WHERE SCHEMA_NAME = ?`, name,
+	).Scan(&cs)
+	return cs, err
+}
+
+func charsetExists(ctx context.Context, db *sql.DB, cs string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+		 FROM information_schema.CHARACTER_SETS
+		 WHERE CHARACTER_SET_NAME = ?`, cs,
+	).Scan(&n)
+	return n > 0, err
+}
+
+func refuse(d desiredDatabase, user, reason string) SyncChange {
+	return SyncChange{Database: d.Name, Action: syncRefuse, User: user, Detail: reason, target: d}
+}
+
+// grantChanges turns a privilege diff into GRANT/REVOKE steps. Privileges
+// outside the whitelist are never revoked blindly.
+func grantChanges(d desiredDatabase, u desiredUser, have []string) []SyncChange {
+	account := quoteUserHost(u.Name, u.Host)
+	missing, extra := diffPrivileges(u.Profile.Privileges, have)
+
+	var changes []SyncChange
+	for _, p := range extra {
+		if !schemaPrivileges[p] {
+			return []SyncChange{refuse(d, account,
+				fmt.Sprintf("holds unexpected privilege '%s' (not tool-managed)", p))}
+		}
+	}
+	if len(missing) > 0 {
+		q := grantSQL(strings.Join(missing, ", "), d.Name, u.Name, u.Host)
+		changes = append(changes, SyncChange{Database: d.Name, Action: syncGrant, User: account,
+			Detail: q, target: d, user: u, stmt: q})
+	}
+	if len(extra) > 0 {
+		q := revokeSQL(strings.Join(extra, ", "), d.Name, u.Name, u.Host)
+		changes = append(changes, SyncChange{Database: d.Name, Action: syncRevoke, User: account,
+			Detail: q, target: d, user: u, stmt: q})
+	}
+	return changes
+}
+
+// diffUser handles an existing database: the account is created if
+// missing, adjusted if tool-managed and refused otherwise.
+func diffUser(ctx context.Context, db *sql.DB, d desiredDatabase, u desiredUser) ([]SyncChange, error) {
+	account := quoteUserHost(u.Name, u.Host)
+
+	exists, err := userExists(ctx, db, u.Name, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []SyncChange{{Database: d.Name, Action: syncCreateUser, User: account,
+			Detail: fmt.Sprintf("create user %s with %s on `%s`", account, u.Profile.Name, d.Name),
+			target: d, user: u}}, nil
+	}
+
+	reason, err := checkToolManaged(ctx, db, u.Name, u.Host, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return []SyncChange{refuse(d, account, reason+" (not tool-managed)")}, nil
+	}
+
+	have, err := schemaGrants(ctx, db, u.Name, u.Host, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return grantChanges(d, u, have), nil
+}
+
+// diffDatabase computes the steps for one manifest entry. Anything that
+// exists but does not look like it was made by this tool is refused.
+func diffDatabase(ctx context.Context, db *sql.DB, d desiredDatabase) ([]SyncChange, error) {
+	if d.Charset != "" {
+		ok, err := charsetExists(ctx, db, d.Charset)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return []SyncChange{refuse(d, "", fmt.Sprintf("unknown character set '%s'", d.Charset))}, nil
+		}
+	}
+
+	dbExists, existing, err := dbOrUserExists(ctx, db, d.Name, []string{d.Owner.Host})
+	if err != nil {
+		return nil, err
+	}
+	ownerExists := len(existing) > 0
+	owner := quoteUserHost(d.Owner.Name, d.Owner.Host)
+
+	switch {
+	case !dbExists && !ownerExists:
+		changes := []SyncChange{{Database: d.Name, Action: syncCreateDatabase, User: owner,
+			Detail: fmt.Sprintf("%s, owner %s with %s", createDatabaseSQL(d.Name, d.Charset, ""), owner, d.Owner.Profile.Name),
+			target: d, user: d.Owner}}
+		for _, u := range d.Users {
+			account := quoteUserHost(u.Name, u.Host)
+			exists, err := userExists(ctx, db, u.Name, u.Host)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				changes = append(changes, refuse(d, account, "user already exists (not tool-managed)"))
+				continue
+			}
+			changes = append(changes, SyncChange{Database: d.Name, Action: syncCreateUser, User: account,
+				Detail: fmt.Sprintf("create user %s with %s on `%s`", account, u.Profile.Name, d.Name),
+				target: d, user: u})
+		}
+		return changes, nil
+
+	case !dbExists:
+		return []SyncChange{refuse(d, owner, "user exists without its database (not tool-managed)")}, nil
+	case !ownerExists:
+		return []SyncChange{refuse(d, owner, "database exists without its user (not tool-managed)")}, nil
+	}
+
+	reason, err := checkToolManaged(ctx, db, d.Owner.Name, d.Owner.Host, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return []SyncChange{refuse(d, owner, reason+" (not tool-managed)")}, nil
+	}
+
+	var changes []SyncChange
+	if d.Charset != "" {
+		cs, err := schemaCharset(ctx, db, d.Name)
+		if err != nil {
+			return nil, err
+		}
+		if cs != d.Charset {
+			q := "ALTER DATABASE " + quoteIdent(d.Name) + " CHARACTER SET " + d.Charset
+			changes = append(changes, SyncChange{Database: d.Name, Action: syncAlterCharset,
+				Detail: fmt.Sprintf("%s (was %s)", q, cs), target: d, stmt: q})
+		}
+	}
+
+	for _, u := range append([]desiredUser{d.Owner}, d.Users...) {
+		uc, err := diffUser(ctx, db, d, u)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, uc...)
+	}
+	return changes, nil
+}
+
+// pruneChanges finds registered databases that are not in the manifest.
+// Each one must still pass checkToolManaged; anything else is left alone.
+func pruneChanges(ctx context.Context, db *sql.DB, desired []desiredDatabase) ([]SyncChange, error) {
+	keep := make(map[string]bool, len(desired))
+	for _, d := range desired {
+		keep[d.Name] = true
+	}
+
+	regs, err := registryEntries(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []SyncChange
+	for _, e := range regs {
+		if e.Role != roleOwner || keep[e.Database] {
+			continue
+		}
+		exists, err := databaseExists(ctx, db, e.Database)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		reason, err := checkToolManaged(ctx, db, e.User, e.Host, e.Database)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			continue
+		}
+		d := desiredDatabase{Name: e.Database, Owner: desiredUser{Name: e.User, Host: e.Host}}
+		account := quoteUserHost(e.User, e.Host)
+		changes = append(changes, SyncChange{Database: e.Database, Action: syncDropDatabase,
+			User:   account,
+			Detail: fmt.Sprintf("drop database `%s` and user %s (not in manifest)", e.Database, account),
+			target: d, user: d.Owner})
+	}
+	return changes, nil
+}
+
+// computeSync diffs the whole manifest against the server.
+func computeSync(db *sql.DB, opts Options, desired []desiredDatabase) ([]SyncChange, error) {
+	changes := []SyncChange{}
+	for _, d := range desired {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		dc, err := diffDatabase(ctx, db, d)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Name, err)
+		}
+		changes = append(changes, dc...)
+	}
+
+	if opts.Prune {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		pc, err := pruneChanges(ctx, db, desired)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, pc...)
+	}
+	return changes, nil
+}
+
+/* ===============================
+   Apply
+================================= */
+
+// applySync executes the changes in order. After a failure the remaining
+// changes of that database are not run; after an interrupt none of the
+// remaining changes are.
+func applySync(ctx context.Context, db *sql.DB, opts Options, changes []SyncChange) {
+	failed := make(map[string]bool)
+	for i := range changes {
+		c := &changes[i]
+		if c.Action == syncRefuse {
+			continue
+		}
+		if failed[c.Database] {
+			c.Status = syncNotRun
+			c.Error = "an earlier change for this database failed"
+			continue
+		}
+		if ctx.Err() != nil {
+			c.Status = syncNotRun
+			c.Error = "interrupted"
+			continue
+		}
+		if err := applyChange(ctx, db, opts, c); err != nil {
+			if interrupted(ctx, err) {
+				c.Status = syncNotRun
+				c.Error = "interrupted"
+				continue
+			}
+			c.Status = syncFailed
+			c.Error = err.Error()
+			failed[c.Database] = true
+			logError(opts.ErrorLogPath, fmt.Sprintf("Sync failed (%s, %s): %v", c.Database, c.Action, err))
+			continue
+		}
+		c.Status = syncApplied
+	}
+}
+
+func applyChange(ctx context.Context, db *sql.DB, opts Options, c *SyncChange) error {
+	switch c.Action {
+	case syncCreateDatabase:
+		o := opts
+		o.UserHost = c.user.Host
+		o.Privileges = c.user.Profile
+		o.Charset = c.target.Charset
+		o.Collation = ""
+		o.Auth = c.user.Auth
+		o.Normalize = false
+		o.ReadOnlyUser = false
+		o.DryRun = false
+		res, err := processDatabase(ctx, db, o, c.target.Name)
+		if err != nil {
+			return err
+		}
+		if res.Status != StatusCreated {
+			return errors.New(res.Message)
+		}
+		c.Password = res.Password
+		return nil
+
+	case syncCreateUser:
+		return createExtraUser(db, opts, c)
+
+	case syncDropDatabase:
+		o := opts
+		o.UserHost = c.user.Host
+		o.Normalize = false
+		o.DryRun = false
+		res, err := deprovisionDatabase(ctx, db, o, c.target.Name)
+		if err != nil {
+			return err
+		}
+		if res.Status != StatusDeleted {
+			return errors.New(res.Message)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	if err := execSQL(ctx, db, c.stmt); err != nil {
+		return err
+	}
+	if c.Action == syncGrant || c.Action == syncRevoke {
+		return updateRegisteredPrivileges(ctx, db, c.Database, c.user)
+	}
+	return nil
+}
+
+// createExtraUser creates a manifest user on an existing database. If the
+// grant fails the user is dropped again.
+func createExtraUser(db *sql.DB, opts Options, c *SyncChange) error {
+	u := c.user
+	var password string
+	if u.Auth.usesPassword() {
+		var err error
+		if password, err = opts.PasswordPolicy.generate(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	reason, err := checkAuthPlugins(ctx, db, u.Auth)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return errors.New(reason)
+	}
+
+	lock, err := lockJournal(opts.JournalPath, false)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	// The database is not recorded as created, so recover only ever
+	// drops the user.
+	j, err := beginCreateJournal(opts, c.Database, []string{u.Name}, []string{u.Host})
+	if err != nil {
+		return err
+	}
+	account := quoteUserHost(u.Name, u.Host)
+	created := false
+	rollback := func() {
+		if created {
+			if err := execSQL(ctx, db, "DROP USER "+account); err != nil {
+				logError(opts.ErrorLogPath, fmt.Sprintf("Rollback of %s incomplete (run recover): %v", account, err))
+				return
+			}
+		}
+		j.end(opts, journalRolledBack, "")
+	}
+
+	if err := j.step(stepCreateUser, u.Name, u.Host); err != nil {
+		rollback()
+		return err
+	}
+	if err := execSQL(ctx, db, createUserSQL(u.Name, u.Host, u.Auth, password)); err != nil {
+		rollback()
+		return fmt.Errorf("create user: %w", err)
+	}
+	created = true
+
+	if err := j.step(stepGrant, u.Name, u.Host); err != nil {
+		rollback()
+		return err
+	}
+	if err := execSQL(ctx, db, grantSQL(u.Profile.grantList(), c.Database, u.Name, u.Host)); err != nil {
+		rollback()
+		return fmt.Errorf("grant privileges: %w", err)
+	}
+
+	entry := newRegistryEntry(opts, c.Database, u.Name, u.Host, roleExtra, u.Profile.Name, u.Profile.grantList())
+	if err := j.step(stepRegister, "", ""); err != nil {
+		rollback()
+		return err
+	}
+	if err := recordAccounts(ctx, db, []RegistryEntry{entry}); err != nil {
+		rollback()
+		return err
+	}
+	j.end(opts, journalDone, "")
+	c.Password = password
+
+	if opts.ExportCSV && password != "" {
+		if err := saveToCSV(opts.CSVPath, c.Database, u.Name, password); err != nil {
+			logError(opts.ErrorLogPath, fmt.Sprintf("CSV export failed (%s): %v", u.Name, err))
+		}
+	}
+	return nil
+}
+
+/* ===============================
+   Output
+================================= */
+
+type SyncSummary struct {
+	Changes int `json:"changes"`
+	Refused int `json:"refused"`
+	Applied int `json:"applied"`
+	Failed  int `json:"failed"`
+}
+
+type SyncReport struct {
+	Manifest string       `json:"manifest"`
+	Apply    bool         `json:"apply"`
+	Changes  []SyncChange `json:"changes"`
+	Summary  SyncSummary  `json:"summary"`
+}
+
+func summarizeSync(changes []SyncChange) SyncSummary {
+	var s SyncSummary
+	for _, c := range changes {
+		switch {
+		case c.Action == syncRefuse:
+			s.Refused++
+			continue
+		case c.Status == syncApplied:
+			s.Applied++
+		case c.Status == syncFailed || c.Status == syncNotRun:
+			s.Failed++
+		}
+		s.Changes++
+	}
+	return s
+}
+
+var syncMarkers = map[string]string{
+	syncCreateDatabase: "+",
+	syncCreateUser:     "+",
+	syncGrant:          "~",
+	syncRevoke:         "~",
+	syncAlterCharset:   "~",
+	syncDropDatabase:   "-",
+	syncRefuse:         "!",
+}
+
+func printSync(w io.Writer, opts Options, changes []SyncChange, apply bool) {
+	for _, c := range changes {
+		line := c.Detail
+		if c.Action == syncRefuse {
+			line = fmt.Sprintf("%s: refused: %s", c.Database, c.Detail)
+			if c.User != "" {
+				line = fmt.Sprintf("%s: refused: %s %s", c.Database, c.User, c.Detail)
+			}
+		}
+		fmt.Fprintf(w, "%s %s\n", syncMarkers[c.Action], line)
+
+		switch c.Status {
+		case syncApplied:
+			if c.Password != "" {
+				fmt.Fprintf(w, "    Password: %s\n", c.Password)
+			}
+		case syncFailed, syncNotRun:
+			fmt.Fprintf(w, "    ❌ %s\n", c.Error)
+		}
+	}
+
+	s := summarizeSync(changes)
+	if s.Changes == 0 && s.Refused == 0 {
+		fmt.Fprintln(w, "✅ Server matches the manifest.")
+		return
+	}
+	if apply {
+		fmt.Fprintf(w, "Applied %d of %d changes, %d failed, %d refused.\n", s.Applied, s.Changes, s.Failed, s.Refused)
+		if opts.ExportCSV && s.Applied > 0 {
+			fmt.Fprintf(w, "   Exported:  %s\n", opts.CSVPath)
+		}
+		return
+	}
+	fmt.Fprintf(w, "%d changes, %d refused. Run with -apply to execute.\n", s.Changes, s.Refused)
+}
+
+// runSync loads the manifest, prints the diff and applies it with -apply.
+// It returns the summary so main can pick the exit code.
+func runSync(ctx context.Context, db *sql.DB, opts Options) (SyncSummary, error) {
+	m, err := loadManifest(opts.Manifest)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+	sections, err := profileSections(opts.ConfigPath)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+	desired, err := resolveManifest(m, opts, sections)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+
+	changes, err := computeSync(db, opts, desired)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+
+	if opts.Apply {
+		applySync(ctx, db, opts, changes)
+	}
+
+	switch opts.Output {
+	case outputJSON:
+		err = writeJSON(os.Stdout, SyncReport{
+			Manifest: opts.Manifest,
+			Apply:    opts.Apply,
+			Changes:  changes,
+			Summary:  summarizeSync(changes),
+		})
+	case outputJSONL:
+		enc := json.NewEncoder(os.Stdout)
+		for _, c := range changes {
+			if err = enc.Encode(c); err != nil {
+				break
+			}
+		}
+	default:
+		printSync(os.Stdout, opts, changes, opts.Apply)
+	}
+	return summarizeSync(changes), err
+}
@@ -0,0 +1,122 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	yml := filepath.Join(dir, "tenants.yaml")
+	writeFile(t, yml, `
+defaults:
+  host: app.internal
+databases:
+  - name: shop.example.com
+    charset: utf8mb4
+    users:
+      - name: shop_report
+        profile: readonly
+`)
+	m, err := loadManifest(yml)
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if m.Defaults.Host != "app.internal" || len(m.Databases) != 1 || m.Databases[0].Users[0].Profile != "readonly" {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+
+	js := filepath.Join(dir, "tenants.json")
+	writeFile(t, js, `{"databases": [{"name": "blog", "profile": "readwrite"}]}`)
+	if m, err := loadManifest(js); err != nil || m.Databases[0].Profile != "readwrite" {
+		t.Fatalf("json: %+v, %v", m, err)
+	}
+
+	writeFile(t, yml, "databases:\n  - name: shop\n    profiel: owner\n")
+	if _, err := loadManifest(yml); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestResolveManifest(t *testing.T) {
+	opts := Options{Normalize: true, UserHost: "localhost"}
+	sections := map[string]map[string]string{}
+
+	m := &Manifest{
+		Defaults: ManifestDefaults{Profile: "readwrite", Charset: "UTF8MB4"},
+		Databases: []ManifestDatabase{
+			{Name: "shop.example.com", Users: []ManifestUser{{Name: "shop_report", Profile: "readonly", Host: "bi.internal"}}},
+			{Name: "blog", Host: "web.internal", Profile: "owner", Charset: "latin1"},
+		},
+	}
+	got, err := resolveManifest(m, opts, sections)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	shop, blog := got[0], got[1]
+	if shop.Name != "shop_example_com" || shop.Charset != "utf8mb4" || shop.Owner.Host != "localhost" ||
+		shop.Owner.Profile.Name != "readwrite" {
+		t.Fatalf("unexpected shop: %+v", shop)
+	}
+	if u := shop.Users[0]; u.Host != "bi.internal" || u.Profile.Privileges[0] != "SELECT" {
+		t.Fatalf("unexpected extra user: %+v", u)
+	}
+	if blog.Owner.Host != "web.internal" || blog.Owner.Profile.Privileges[0] != allPrivileges || blog.Charset != "latin1" {
+		t.Fatalf("unexpected blog: %+v", blog)
+	}
+
+	bad := map[string]*Manifest{
+		"collision": {Databases: []ManifestDatabase{{Name: "my-site.se"}, {Name: "my.site.se"}}},
+		"shared user": {Databases: []ManifestDatabase{
+			{Name: "a", Users: []ManifestUser{{Name: "b", Profile: "readonly"}}},
+			{Name: "b"},
+		}},
+		"no profile":      {Databases: []ManifestDatabase{{Name: "a", Users: []ManifestUser{{Name: "x"}}}}},
+		"bad charset":     {Databases: []ManifestDatabase{{Name: "a", Charset: "utf8; DROP"}}},
+		"wildcard host":   {Databases: []ManifestDatabase{{Name: "a", Host: "%"}}},
+		"unknown profile": {Databases: []ManifestDatabase{{Name: "a", Profile: "superuser"}}},
+	}
+	for name, m := range bad {
+		if _, err := resolveManifest(m, opts, sections); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestGrantChanges(t *testing.T) {
+	d := desiredDatabase{Name: "shop"}
+	u := desiredUser{Name: "shop", Host: "localhost", Profile: PrivilegeProfile{Name: "readonly", Privileges: []string{"SELECT", "SHOW VIEW"}}}
+
+	changes := grantChanges(d, u, []string{"SELECT", "INSERT"})
+	if len(changes) != 2 || changes[0].Action != syncGrant || changes[1].Action != syncRevoke {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if changes[0].stmt != "GRANT SHOW VIEW ON `shop`.* TO 'shop'@'localhost'" ||
+		changes[1].stmt != "REVOKE INSERT ON `shop`.* FROM 'shop'@'localhost'" {
+		t.Fatalf("unexpected statements: %q / %q", changes[0].stmt, changes[1].stmt)
+	}
+
+	changes = grantChanges(d, u, []string{"SELECT", "SHOW VIEW", "FANCY NEW PRIV"})
+	if len(changes) != 1 || changes[0].Action != syncRefuse || !strings.Contains(changes[0].Detail, "FANCY NEW PRIV") {
+		t.Fatalf("expected refusal, got %+v", changes)
+	}
+
+	if changes := grantChanges(d, u, []string{"SHOW VIEW", "SELECT"}); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
@@ -0,0 +1,143 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Name under which our TLS config is registered with the mysql driver.
+const tlsConfigName = "mariadb-tool"
+
+const (
+	sslModeDisabled       = "disabled"
+	sslModePreferred      = "preferred"
+	sslModeRequired       = "required"
+	sslModeVerifyIdentity = "verify-identity"
+)
+
+// resolveSSLMode returns the effective ssl-mode. Without ssl-mode the
+// connection stays unencrypted, unless ssl-ca is set: a CA is only useful
+// for verification, so that implies verify-identity.
+func resolveSSLMode(cfg map[string]string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(cfg["ssl-mode"]))
+	switch mode {
+	case "":
+		if cfg["ssl-ca"] != "" {
+			return sslModeVerifyIdentity, nil
+		}
+		return sslModeDisabled, nil
+	case sslModeDisabled, sslModePreferred, sslModeRequired, sslModeVerifyIdentity:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid ssl-mode '%s' (allowed: disabled, preferred, required, verify-identity)", mode)
+}
+
+// setupTLS configures TLS on c according to the ssl-* keys and returns
+// the effective mode. Custom configs are registered with the driver.
+func setupTLS(c *mysql.Config, cfg map[string]string, serverName string) (string, error) {
+	mode, err := resolveSSLMode(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case sslModeDisabled:
+		c.TLSConfig = "false"
+		return mode, nil
+	case sslModePreferred:
+		// Opportunistic: encrypt if the server offers it, never verify.
+		c.TLSConfig = "preferred"
+		return mode, nil
+	}
+
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var pool *x509.CertPool
+	if caFile := cfg["ssl-ca"]; caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return "", fmt.Errorf("ssl-ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("ssl-ca: no PEM certificates found in %s", caFile)
+		}
+	}
+
+	certFile, keyFile := cfg["ssl-cert"], cfg["ssl-key"]
+	switch {
+	case certFile != "" && keyFile != "":
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return "", fmt.Errorf("ssl-cert/ssl-key: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	case certFile != "" || keyFile != "":
+		return "", errors.New("ssl-cert and ssl-key must be set together")
+	}
+
+	switch mode {
+	case sslModeRequired:
+		// Encryption is mandatory; the hostname is not checked. With ssl-ca
+		// the chain is still verified against it.
+		tc.InsecureSkipVerify = true
+		if pool != nil {
+			tc.VerifyPeerCertificate = verifyChainOnly(pool)
+		}
+	case sslModeVerifyIdentity:
+		if serverName == "" {
+			return "", errors.New("ssl-mode verify-identity needs hostname")
+		}
+		tc.RootCAs = pool // nil: system roots
+		tc.ServerName = serverName
+	}
+
+	if err := mysql.RegisterTLSConfig(tlsConfigName, tc); err != nil {
+		return "", err
+	}
+	c.TLSConfig = tlsConfigName
+	return mode, nil
+}
+
+// verifyChainOnly checks the server certificate against pool without
+// matching the hostname.
+func verifyChainOnly(pool *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{Roots: pool, Intermediates: intermediates})
+		return err
+	}
+}
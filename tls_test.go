@@ -0,0 +1,100 @@
+// mariadb-tool
+// Copyright (C) 2026 P-A Jonasson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY.
+//
+// See the LICENSE file in the project root for details.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSSLMode(t *testing.T) {
+	cases := []struct {
+		cfg  map[string]string
+		want string
+	}{
+		{map[string]string{}, sslModeDisabled},
+		{map[string]string{"ssl-ca": "/x/ca.pem"}, sslModeVerifyIdentity},
+		{map[string]string{"ssl-mode": "Required"}, sslModeRequired},
+		{map[string]string{"ssl-mode": "preferred", "ssl-ca": "/x/ca.pem"}, sslModePreferred},
+	}
+	for _, c := range cases {
+		got, err := resolveSSLMode(c.cfg)
+		if err != nil || got != c.want {
+			t.Fatalf("resolveSSLMode(%v)=%q err=%v, want %q", c.cfg, got, err, c.want)
+		}
+	}
+	if _, err := resolveSSLMode(map[string]string{"ssl-mode": "verify-ca"}); err == nil {
+		t.Fatal("expected error for unsupported ssl-mode")
+	}
+}
+
+func TestMySQLConfigNetwork(t *testing.T) {
+	c, err := mysqlConfig(map[string]string{"username": "root", "socket": "/run/mysqld/mysqld.sock"})
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	if c.Net != "unix" || c.Addr != "/run/mysqld/mysqld.sock" {
+		t.Fatalf("socket: got %s %s", c.Net, c.Addr)
+	}
+
+	c, err = mysqlConfig(map[string]string{"username": "root", "password": "p@ss/word", "hostname": "db1", "port": "3307"})
+	if err != nil {
+		t.Fatalf("tcp: %v", err)
+	}
+	if c.Net != "tcp" || c.Addr != "db1:3307" || c.Passwd != "p@ss/word" {
+		t.Fatalf("tcp: got %s %s", c.Net, c.Addr)
+	}
+
+	if _, err := mysqlConfig(map[string]string{"username": "root", "hostname": "db1"}); err == nil {
+		t.Fatal("expected error without port or socket")
+	}
+}
+
+func TestMySQLConfigTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	base := map[string]string{"username": "root", "hostname": "db1", "port": "3306"}
+	bad := []map[string]string{
+		{"ssl-ca": filepath.Join(dir, "missing.pem")},
+		{"ssl-ca": notPEM},
+		{"ssl-mode": "required", "ssl-cert": filepath.Join(dir, "client.pem")},
+		{"ssl-mode": "required", "ssl-cert": notPEM, "ssl-key": notPEM},
+	}
+	for _, extra := range bad {
+		cfg := map[string]string{}
+		for k, v := range base {
+			cfg[k] = v
+		}
+		for k, v := range extra {
+			cfg[k] = v
+		}
+		if _, err := mysqlConfig(cfg); err == nil {
+			t.Fatalf("expected error for %v", extra)
+		}
+	}
+
+	cfg := map[string]string{"username": "root", "hostname": "db1", "port": "3306", "ssl-mode": "required"}
+	c, err := mysqlConfig(cfg)
+	if err != nil {
+		t.Fatalf("required without CA: %v", err)
+	}
+	if c.TLSConfig != tlsConfigName {
+		t.Fatalf("expected registered TLS config, got %q", c.TLSConfig)
+	}
+}
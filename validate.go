@@ -33,6 +33,9 @@ var (
 	// NEW: raw input allowed chars when -normalize=true
 	// Allows typical domain-ish inputs: letters, digits, dot, dash, underscore
 	rawNormalizeAllowedRe = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+	// Character set and collation names are spliced into DDL unquoted.
+	charsetNameRe = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
 )
 
 func validateIdentifier(name string) error {
@@ -49,6 +52,20 @@ func validateIdentifier(name string) error {
 	return nil
 }
 
+func validateCharset(cs string) error {
+	if !charsetNameRe.MatchString(cs) {
+		return fmt.Errorf("invalid character set '%s' (allowed: a-z 0-9 _)", cs)
+	}
+	return nil
+}
+
+func validateCollation(c string) error {
+	if !charsetNameRe.MatchString(c) {
+		return fmt.Errorf("invalid collation '%s' (allowed: a-z 0-9 _)", c)
+	}
+	return nil
+}
+
 func quoteIdent(ident string) string {
 	return "`" + ident + "`"
 }
@@ -89,12 +106,21 @@ func normalizeName(input string) string {
 		return s
 	}
 
+	return hashSuffixed(s, raw)
+}
+
+// hashSuffixed appends "_" + 8 hex chars of sha1(raw) to base, truncating
+// base so the result fits maxIdentLen.
+func hashSuffixed(base, raw string) string {
 	h := sha1.Sum([]byte(raw))
 	suffix := hex.EncodeToString(h[:])[:8] // 8 hex chars
 
 	baseLen := maxIdentLen - 1 - len(suffix) // "_" + suffix
 	if baseLen < 1 {
-		return s[:maxIdentLen]
+		return base[:maxIdentLen]
+	}
+	if len(base) > baseLen {
+		base = base[:baseLen]
 	}
-	return s[:baseLen] + "_" + suffix
+	return base + "_" + suffix
 }
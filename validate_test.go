@@ -13,7 +13,12 @@
 
 package main
 
-import "testing"
+import (
+	"flag"
+	"reflect"
+	"slices"
+	"testing"
+)
 
 func TestValidateIdentifier(t *testing.T) {
 	ok := []string{"abc", "ABC_123", "user_01", "a0_b1"}
@@ -70,3 +75,99 @@ func TestNormalizeNameTruncatesWithHash(t *testing.T) {
 		t.Fatalf("expected hash suffix pattern in %q", got)
 	}
 }
+
+func TestUserHosts(t *testing.T) {
+	cases := map[string][]string{
+		"":                      {"localhost"},
+		"localhost":             {"localhost"},
+		"localhost, 10.0.0.5,,": {"localhost", "10.0.0.5"},
+		"app.example.com,localhost,app.example.com": {"app.example.com", "localhost"},
+	}
+	for in, want := range cases {
+		if got := userHosts(in); !slices.Equal(got, want) {
+			t.Fatalf("userHosts(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHostListFlag(t *testing.T) {
+	v := "localhost"
+	f := &hostListFlag{v: &v}
+	_ = f.Set("10.0.0.5")
+	_ = f.Set("app.example.com,10.0.0.6")
+	if v != "10.0.0.5,app.example.com,10.0.0.6" {
+		t.Fatalf("got %q", v)
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	cases := map[string]string{
+		"10.20.0.0/16":   "10.20.0.0/255.255.0.0",
+		" 10.0.0.0/8 ":   "10.0.0.0/255.0.0.0",
+		"192.168.1.0/25": "192.168.1.0/255.255.255.128",
+		"10.0.0.5/32":    "10.0.0.5",
+		"2001:DB8::1":    "2001:db8::1",
+		"fd00::/8":       "fd00::/8",
+		"10.20.0.1/16":   "10.20.0.1/16",
+		"app.internal":   "app.internal",
+		"10.0.%":         "10.0.%",
+	}
+	for in, want := range cases {
+		if got := canonicalHost(in); got != want {
+			t.Fatalf("canonicalHost(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateUserHostAddresses(t *testing.T) {
+	ok := []string{
+		"localhost", "db-1.example.com", "10.0.0.5", "::1", "2001:db8::1",
+		"10.20.0.0/255.255.0.0", "192.168.1.128/255.255.255.128",
+	}
+	bad := []string{
+		"10.0.0.256", "10.0.0", "2001:db8::g", "fe80::1%eth0",
+		"10.20.0.0/16", "10.20.0.1/255.255.0.0", "10.20.0.0/255.0.255.0",
+		"0.0.0.0/0.0.0.0", "fd00::/8", "10.0.0.0/x", "10.%",
+	}
+	for _, h := range ok {
+		if err := validateUserHost("app", h, false); err != nil {
+			t.Fatalf("expected %q to be valid: %v", h, err)
+		}
+	}
+	for _, h := range bad {
+		if err := validateUserHost("app", h, false); err == nil {
+			t.Fatalf("expected %q to be rejected", h)
+		}
+	}
+	for _, h := range []string{"0.0.0.0/0.0.0.0", "10.%"} {
+		if err := validateUserHost("app", h, true); err != nil {
+			t.Fatalf("expected %q to be valid with wildcards allowed: %v", h, err)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	cases := []struct {
+		args    []string
+		wantPos []string
+		dryRun  bool
+	}{
+		{[]string{"shop", "-dry-run"}, []string{"shop"}, true},
+		{[]string{"-dry-run", "shop"}, []string{"shop"}, true},
+		{[]string{"shop", "extra"}, []string{"shop", "extra"}, false},
+		{[]string{"--", "-dry-run"}, []string{"-dry-run"}, false},
+		{[]string{"shop", "--", "-x", "y"}, []string{"shop", "-x", "y"}, false},
+		{nil, nil, false},
+	}
+	for _, c := range cases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "")
+		pos, err := parseArgs(fs, c.args)
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if !reflect.DeepEqual(pos, c.wantPos) || *dryRun != c.dryRun {
+			t.Fatalf("%v: pos=%v dry-run=%v", c.args, pos, *dryRun)
+		}
+	}
+}